@@ -5,37 +5,74 @@ import (
 	"log"
 	"math"
 	"os"
+	"slices"
+	"sync/atomic"
 
 	"gonum.org/v1/hdf5"
 )
 
 type Simulator struct {
-	Dt         float64
-	T          float64
-	Count      int
-	N          int
-	Id         []int
-	Pos        []Vector
-	Vel        []Vector
-	Gravity    Vector
-	RegionSize float64
-	GridSize   float64
-	Grid       [][]int
+	Dt          float64
+	T           float64
+	Count       int
+	N           int
+	Id          []int
+	Mass        []float64
+	Charge      []float64
+	Species     []Species
+	Pos         []Vector
+	Vel         []Vector
+	Gravity     Vector
+	RegionSize  float64
+	GridSize    float64
+	ForceFields []ForceField
+	Integrator  Integrator
+	Parallel    Parallel
+
+	// 셀 목록 (MakeGrid): 셀 c의 파티클은 ParticleIndex[CellStart[c]:CellStart[c+1]] (오름차순)
+	CellStart     []int // 셀별 시작 위치, 길이 셀 수 + 1
+	ParticleIndex []int // 셀 순서로 정렬한 파티클 번호, 길이 N
+
+	// MakeGrid가 호출마다 재사용하는 작업 버퍼
+	gridCells  []int   // 파티클별 셀 번호
+	gridCounts []int32 // 셀별 파티클 수 (넣기 단계의 커서로 재사용)
+
+	fieldsVersion int     // AddForceField마다 증가 (적분기 가속도 캐시 무효화)
+	wrapLength    float64 // 마지막 PeriodicBoundary의 주기 (적분기 가속도 캐시가 주기 이동을 구분)
+
+	loops simulatorLoops // 병렬 루프 몸체 (Parallel.run)
+}
+
+// simulatorLoops는 Simulator 메서드와 적분기가 Parallel.run에 넘기는 루프 몸체입니다.
+// 인자를 필드에 담아 포인터로 넘기므로 매 스텝 도는 루프에 클로저 할당이 없습니다.
+type simulatorLoops struct {
+	axpy     axpyLoop
+	boundary boundaryLoop
+	grid     gridLoop
 }
 
 func NewSimulator(Dt float64, Id []int, Pos, Vel []Vector, Gravity Vector) *Simulator {
+	Mass := make([]float64, len(Pos))
+	for i := range Mass {
+		Mass[i] = 1.0
+	}
 	return &Simulator{
-		Dt:         Dt,
-		T:          0.0,
-		Count:      0,
-		N:          len(Pos),
-		Id:         Id,
-		Pos:        Pos,
-		Vel:        Vel,
-		Gravity:    Gravity,
-		RegionSize: 0.0,
-		GridSize:   0.0,
-		Grid:       [][]int{},
+		Dt:          Dt,
+		T:           0.0,
+		Count:       0,
+		N:           len(Pos),
+		Id:          Id,
+		Mass:        Mass,
+		Charge:      make([]float64, len(Pos)),
+		Species:     []Species{},
+		Pos:         Pos,
+		Vel:         Vel,
+		Gravity:     Gravity,
+		RegionSize:  0.0,
+		GridSize:    0.0,
+		ForceFields: []ForceField{},
+		Integrator:  &Euler{},
+		Parallel:    Parallel{},
 	}
 }
 
@@ -43,23 +80,21 @@ func (simulator *Simulator) Step() {
 	simulator.Count++
 	simulator.T = float64(simulator.Count) * simulator.Dt
 
-	x_ := make([]Vector, simulator.N)
-	v_ := make([]Vector, simulator.N)
-	for i := 0; i < simulator.N; i++ {
-		new_vel := simulator.Vel[i].Add(simulator.Gravity.Mul(simulator.Dt))
-		v_[i] = new_vel
-		x_[i] = simulator.Pos[i].Add(new_vel.Mul(simulator.Dt))
-	}
-
-	for i := 0; i < simulator.N; i++ {
-		simulator.Pos[i] = x_[i]
-		simulator.Vel[i] = v_[i]
+	if simulator.Integrator == nil {
+		simulator.Integrator = &Euler{}
 	}
+	simulator.Integrator.Step(simulator)
 }
 
 func (simulator *Simulator) SolidBoundary(length float64) {
+	simulator.loops.boundary = boundaryLoop{simulator, length, true}
+	simulator.Parallel.run(simulator.N, &simulator.loops.boundary)
+}
+
+// solidBoundary는 파티클 [lo, hi)를 SolidBoundary의 벽에서 반사합니다.
+func (simulator *Simulator) solidBoundary(lo, hi int, length float64) {
 	half_length := length / 2
-	for i := 0; i < simulator.N; i++ {
+	for i := lo; i < hi; i++ {
 		for {
 			is_collision := false
 			if simulator.Pos[i].X < -half_length {
@@ -142,8 +177,15 @@ func (simulator *Simulator) SolidBoundary(length float64) {
 }
 
 func (simulator *Simulator) PeriodicBoundary(length float64) {
+	simulator.wrapLength = length
+	simulator.loops.boundary = boundaryLoop{simulator, length, false}
+	simulator.Parallel.run(simulator.N, &simulator.loops.boundary)
+}
+
+// periodicBoundary는 파티클 [lo, hi)를 PeriodicBoundary의 주기 상자 안으로 옮깁니다.
+func (simulator *Simulator) periodicBoundary(lo, hi int, length float64) {
 	half_length := length / 2
-	for i := 0; i < simulator.N; i++ {
+	for i := lo; i < hi; i++ {
 		if simulator.Pos[i].X > half_length {
 			simulator.Pos[i].X = simulator.Pos[i].X - length
 		}
@@ -165,101 +207,187 @@ func (simulator *Simulator) PeriodicBoundary(length float64) {
 	}
 }
 
-func (simulator *Simulator) MakeGrid() {
-	n := int(simulator.RegionSize/simulator.GridSize + 1)
-	grid := make([][]int, n*n*n)
-	for i := 0; i < simulator.N; i++ {
-		x := int((simulator.Pos[i].X + simulator.RegionSize/2) / simulator.GridSize)
-		y := int((simulator.Pos[i].Y + simulator.RegionSize/2) / simulator.GridSize)
-		z := int((simulator.Pos[i].Z + simulator.RegionSize/2) / simulator.GridSize)
-		grid[x+y*n+z*n*n] = append(grid[x+y*n+z*n*n], i)
-	}
-	simulator.Grid = grid
+// boundaryLoop은 SolidBoundary(solid)와 PeriodicBoundary의 병렬 루프입니다.
+type boundaryLoop struct {
+	sim    *Simulator
+	length float64
+	solid  bool
 }
 
-func Mod(a, b int) int {
-	result := a % b
-	if result < 0 {
-		result += b
+func (l *boundaryLoop) run(_, lo, hi int) {
+	if l.solid {
+		l.sim.solidBoundary(lo, hi, l.length)
+	} else {
+		l.sim.periodicBoundary(lo, hi, l.length)
 	}
-	return result
 }
 
-func removeValue(slice []int, value int) []int {
-	for i, v := range slice {
-		if v == value {
-			return append(slice[:i], slice[i+1:]...)
-		}
+// MakeGrid는 파티클을 GridSize 크기의 이웃 탐색 셀에 나눠 평탄한 셀 목록
+// (CellStart, ParticleIndex)을 만듭니다 (ForEachNeighbor, GetNearAtoms 참고).
+// 셀별 계수 정렬입니다: 셀 번호 계산과 셀별 개수 세기, 셀에 넣기, 셀 안 정렬을 sim.Parallel로 나눠
+// 실행하며 (누적합만 직렬), 각 셀의 파티클 번호는 직렬로 만든 것과 같은 오름차순입니다.
+//
+// 배열은 호출마다 재사용하므로 셀 수와 N이 그대로이면 새로 할당하지 않습니다.
+func (simulator *Simulator) MakeGrid() {
+	n := simulator.cellsPerSide()
+	simulator.gridCells = resize(simulator.gridCells, simulator.N)
+	simulator.gridCounts = resize(simulator.gridCounts, n*n*n)
+	simulator.CellStart = resize(simulator.CellStart, n*n*n+1)
+	simulator.ParticleIndex = resize(simulator.ParticleIndex, simulator.N)
+	loop := &simulator.loops.grid
+	*loop = gridLoop{sim: simulator, n: n}
+	clear(simulator.gridCounts)
+	simulator.Parallel.run(simulator.N, loop)
+
+	start, counts := simulator.CellStart, simulator.gridCounts
+	start[0] = 0
+	for c, k := range counts {
+		start[c+1] = start[c] + int(k)
+		counts[c] = 0
 	}
-	return slice
+	loop.phase = gridFill
+	simulator.Parallel.run(simulator.N, loop)
+
+	loop.phase = gridSort
+	simulator.Parallel.run(n*n*n, loop)
 }
 
-func (simulator *Simulator) GetNearAtoms(atom_index int, is_periodic ...bool) []int {
+// gridLoop은 MakeGrid의 세 병렬 루프입니다 (phase 순서대로 실행).
+type gridLoop struct {
+	sim   *Simulator
+	n     int // 한 변 셀 수
+	phase int
+}
+
+const (
+	gridCount = iota // 파티클별 셀 번호와 셀별 개수
+	gridFill         // 셀에 넣기
+	gridSort         // 셀 안 정렬
+)
 
-	if len(is_periodic) == 0 {
-		is_periodic = []bool{false}
+func (l *gridLoop) run(_, lo, hi int) {
+	sim, n := l.sim, l.n
+	cells, counts, start, members := sim.gridCells, sim.gridCounts, sim.CellStart, sim.ParticleIndex
+	switch l.phase {
+	case gridCount:
+		for i := lo; i < hi; i++ {
+			x, y, z := sim.cellOf(i)
+			cells[i] = x + y*n + z*n*n
+			atomic.AddInt32(&counts[cells[i]], 1)
+		}
+	case gridFill:
+		for i := lo; i < hi; i++ {
+			c := cells[i]
+			members[start[c]+int(atomic.AddInt32(&counts[c], 1))-1] = i
+		}
+	case gridSort:
+		for c := lo; c < hi; c++ {
+			slices.Sort(members[start[c]:start[c+1]])
+		}
 	}
+}
+
+// cellsPerSide는 이웃 탐색 격자의 한 변 셀 수입니다.
+func (simulator *Simulator) cellsPerSide() int {
+	return int(simulator.RegionSize/simulator.GridSize + 1)
+}
 
-	n := int(simulator.RegionSize/simulator.GridSize + 1)
+// cellOf는 파티클 atom_index가 속한 셀의 좌표를 반환합니다.
+func (simulator *Simulator) cellOf(atom_index int) (int, int, int) {
 	x := int((simulator.Pos[atom_index].X + simulator.RegionSize/2) / simulator.GridSize)
 	y := int((simulator.Pos[atom_index].Y + simulator.RegionSize/2) / simulator.GridSize)
 	z := int((simulator.Pos[atom_index].Z + simulator.RegionSize/2) / simulator.GridSize)
+	return x, y, z
+}
+
+// Cell은 셀 c = x + y·n + z·n² 의 파티클 번호를 오름차순으로 반환합니다 (ParticleIndex의 부분 슬라이스).
+func (simulator *Simulator) Cell(c int) []int {
+	return simulator.ParticleIndex[simulator.CellStart[c]:simulator.CellStart[c+1]]
+}
+
+func Mod(a, b int) int {
+	result := a % b
+	if result < 0 {
+		result += b
+	}
+	return result
+}
+
+// GetNearAtoms는 파티클 atom_index 주변 셀의 파티클 번호를 새 배열로 반환합니다 (자기 자신 제외).
+// ForEachNeighbor가 방문하는 순서와 같습니다.
+func (simulator *Simulator) GetNearAtoms(atom_index int, is_periodic ...bool) []int {
 	indices := []int{}
+	simulator.ForEachNeighbor(atom_index, len(is_periodic) > 0 && is_periodic[0], func(j int) {
+		indices = append(indices, j)
+	})
+	return indices
+}
 
-	if is_periodic[0] == false {
-		for i := x - 1; i <= x+1; i++ {
-			for j := y - 1; j <= y+1; j++ {
-				for k := z - 1; k <= z+1; k++ {
-					if 0 <= i && i < n && 0 <= j && j < n && 0 <= k && k < n {
-						indices = append(indices, simulator.Grid[i+j*n+k*n*n]...)
-					}
-				}
+// ForEachNeighbor는 파티클 atom_index 주변 셀(비주기: 3³, 주기: 경계 셀에서는 한 칸 더, 겹치는 셀은 한 번)의
+// 파티클마다 visit(j)를 호출합니다 (자기 자신 제외). 셀 목록을 직접 읽으므로 할당이 없습니다.
+//
+// 사전 조건: sim.MakeGrid()가 호출된 상태여야 합니다.
+func (simulator *Simulator) ForEachNeighbor(atom_index int, is_periodic bool, visit func(j int)) {
+	var cells [64]int
+	self := false
+	for _, c := range simulator.neighborCells(atom_index, is_periodic, &cells) {
+		for _, j := range simulator.Cell(c) {
+			if j == atom_index && !self {
+				self = true
+				continue
 			}
+			visit(j)
 		}
-	} else {
-		x_min := 1
-		if x == 0 {
-			x_min = 2
-		}
-		y_min := 1
-		if y == 0 {
-			y_min = 2
-		}
-		z_min := 1
-		if z == 0 {
-			z_min = 2
-		}
-		x_max := 1
-		if x == n-2 {
-			x_max = 2
-		}
-		y_max := 1
-		if y == n-2 {
-			y_max = 2
-		}
-		z_max := 1
-		if z == n-2 {
-			z_max = 2
-		}
-		for i := x - x_min; i <= x+x_max; i++ {
-			for j := y - y_min; j <= y+y_max; j++ {
-				for k := z - z_min; k <= z+z_max; k++ {
-					i_ := Mod(i, n)
-					j_ := Mod(j, n)
-					k_ := Mod(k, n)
-					// if atom_index == 0 {
-					// 	fmt.Println(n)
-					// 	fmt.Println("i, j, k:", i_, j_, k_)
-
-					// }
-
-					indices = append(indices, simulator.Grid[i_+j_*n+k_*n*n]...)
-				}
+	}
+}
+
+// neighborCells는 ForEachNeighbor가 훑는 셀 번호를 순서대로 cells에 채워 반환합니다 (최대 4³).
+// 방문자 없이 셀을 직접 순회하는 루프(PairForce)에 씁니다. 축마다 감은 셀 좌표의 중복을 없애므로
+// 한 변의 셀이 4개보다 적은 작은 주기 격자에서도 각 셀은 한 번만 나옵니다.
+func (simulator *Simulator) neighborCells(atom_index int, is_periodic bool, cells *[64]int) []int {
+	n := simulator.cellsPerSide()
+	x, y, z := simulator.cellOf(atom_index)
+	var xs, ys, zs [4]int
+	count := 0
+	for _, i := range neighborAxis(x, n, is_periodic, &xs) {
+		for _, j := range neighborAxis(y, n, is_periodic, &ys) {
+			for _, k := range neighborAxis(z, n, is_periodic, &zs) {
+				cells[count] = i + j*n + k*n*n
+				count++
 			}
 		}
 	}
+	return cells[:count]
+}
 
-	return removeValue(indices, atom_index)
+// neighborAxis는 한 축에서 셀 좌표 x 주변의 훑을 좌표를 axis에 채워 반환합니다 (최대 min(4, n)개).
+// 비주기이면 격자 안의 x-1..x+1 입니다. 주기이면 마지막 셀(n-1)이 영역 밖으로 튀어나온
+// 부분 셀이므로 0과 n-2 셀은 한 칸 더 보며, 감은 좌표가 겹치면 처음 것만 남깁니다.
+func neighborAxis(x, n int, is_periodic bool, axis *[4]int) []int {
+	count := 0
+	if !is_periodic {
+		for i := max(x-1, 0); i <= min(x+1, n-1); i++ {
+			axis[count] = i
+			count++
+		}
+		return axis[:count]
+	}
+
+	lo, hi := x-1, x+1
+	if x == 0 {
+		lo = x - 2
+	}
+	if x == n-2 {
+		hi = x + 2
+	}
+	for i := lo; i <= hi; i++ {
+		c := Mod(i, n)
+		if !slices.Contains(axis[:count], c) {
+			axis[count] = c
+			count++
+		}
+	}
+	return axis[:count]
 }
 
 func (simulator *Simulator) PeriodicDisplacement(atom_index int, another_atom_index int) Vector {
@@ -287,6 +415,34 @@ func (simulator *Simulator) PeriodicDisplacement(atom_index int, another_atom_in
 	return Vector{X: dx, Y: dy, Z: dz}
 }
 
+func (simulator *Simulator) TotalMass() float64 {
+	total := 0.0
+	for i := 0; i < simulator.N; i++ {
+		total += simulator.Mass[i]
+	}
+	return total
+}
+
+func (simulator *Simulator) Momentum() Vector {
+	var momentum Vector
+	for i := 0; i < simulator.N; i++ {
+		momentum = momentum.Add(simulator.Vel[i].Mul(simulator.Mass[i]))
+	}
+	return momentum
+}
+
+func (simulator *Simulator) KineticEnergy() float64 {
+	K := 0.0
+	for i := 0; i < simulator.N; i++ {
+		K += 0.5 * simulator.Mass[i] * simulator.Vel[i].Dot(simulator.Vel[i])
+	}
+	return K
+}
+
+func (simulator *Simulator) TotalEnergy() float64 {
+	return simulator.KineticEnergy() + simulator.PotentialEnergy()
+}
+
 func (simulator *Simulator) Save(directory string) {
 	if _, err := os.Stat(directory); os.IsNotExist(err) {
 		os.Mkdir(directory, os.ModeDir|0755)
@@ -311,6 +467,8 @@ func (simulator *Simulator) Save(directory string) {
 
 	// Dataset 생성
 	CreateDatasetInt(rootGroup, "Id", simulator.Id, []uint{uint(simulator.N)})
+	CreateDatasetFloat(rootGroup, "Mass", simulator.Mass, []uint{uint(simulator.N)})
+	CreateDatasetFloat(rootGroup, "Charge", simulator.Charge, []uint{uint(simulator.N)})
 
 	pos := make([]float64, 3*simulator.N)
 	vel := make([]float64, 3*simulator.N)
@@ -326,9 +484,30 @@ func (simulator *Simulator) Save(directory string) {
 	}
 	CreateDatasetFloat(rootGroup, "Pos", pos, []uint{uint(simulator.N), 3})
 	CreateDatasetFloat(rootGroup, "Vel", vel, []uint{uint(simulator.N), 3})
+
+	// 종 테이블
+	saveSpecies(rootGroup, simulator.Species)
 }
 
-func Read(filename string) (float64, float64, int, int, Vector, []int, []Vector, []Vector) {
+// Snapshot은 HDF5 스냅샷 파일에 저장된 시뮬레이터 상태입니다 (ReadSnapshot).
+type Snapshot struct {
+	Dt      float64
+	T       float64
+	Count   int
+	N       int
+	Gravity Vector
+	Id      []int
+	Mass    []float64
+	Charge  []float64
+	Pos     []Vector
+	Vel     []Vector
+	Species []Species // 종 테이블 (없으면 nil)
+}
+
+// ReadSnapshot은 HDF5 스냅샷의 모든 상태를 읽습니다 (Load의 내부 구현).
+// Mass 데이터셋이 없는 이전 스냅샷은 단위 질량으로, Charge 데이터셋이 없으면 전하 0으로 읽습니다.
+// 종 테이블은 같은 파일 핸들에서 읽으며, 형식이 잘못되었으면 종료합니다.
+func ReadSnapshot(filename string) Snapshot {
 	// HDF5 파일 열기
 	file, err := hdf5.OpenFile(filename, hdf5.F_ACC_RDONLY)
 	if err != nil {
@@ -339,31 +518,59 @@ func Read(filename string) (float64, float64, int, int, Vector, []int, []Vector,
 	rootGroup, _ := file.OpenGroup("/")
 	defer rootGroup.Close()
 
-	dt := ReadAttributeFloat(rootGroup, "Dt")
-	t := ReadAttributeFloat(rootGroup, "T")
-	count := ReadAttributeInt(rootGroup, "Count")
-	N := ReadAttributeInt(rootGroup, "N")
-	gravity := ReadAttributeVector(rootGroup, "Gravity")
-
-	id := ReadDatasetInt(rootGroup, "Id")
+	snapshot := Snapshot{
+		Dt:      ReadAttributeFloat(rootGroup, "Dt"),
+		T:       ReadAttributeFloat(rootGroup, "T"),
+		Count:   ReadAttributeInt(rootGroup, "Count"),
+		N:       ReadAttributeInt(rootGroup, "N"),
+		Gravity: ReadAttributeVector(rootGroup, "Gravity"),
+		Id:      ReadDatasetInt(rootGroup, "Id"),
+		Pos:     ReadDatasetVector(rootGroup, "Pos"),
+		Vel:     ReadDatasetVector(rootGroup, "Vel"),
+	}
 
-	pos := ReadDatasetVector(rootGroup, "Pos")
-	vel := ReadDatasetVector(rootGroup, "Vel")
+	// Mass 데이터셋이 없는 이전 스냅샷은 단위 질량으로 읽음
+	if rootGroup.LinkExists("Mass") {
+		snapshot.Mass = ReadDatasetFloat(rootGroup, "Mass")
+	} else {
+		snapshot.Mass = make([]float64, snapshot.N)
+		for i := range snapshot.Mass {
+			snapshot.Mass[i] = 1.0
+		}
+	}
+	// Charge 데이터셋이 없으면 중성
+	if rootGroup.LinkExists("Charge") {
+		snapshot.Charge = ReadDatasetFloat(rootGroup, "Charge")
+	} else {
+		snapshot.Charge = make([]float64, snapshot.N)
+	}
+	// 종 테이블
+	if snapshot.Species, err = readSpecies(rootGroup); err != nil {
+		log.Fatalf("종 테이블을 읽을 수 없습니다: %v", err)
+	}
+	return snapshot
+}
 
-	return dt, t, count, N, gravity, id, pos, vel
+// Read는 HDF5 스냅샷의 기본 상태를 읽습니다. 질량과 전하까지 필요하면 ReadSnapshot을 사용하세요.
+func Read(filename string) (float64, float64, int, int, Vector, []int, []Vector, []Vector) {
+	s := ReadSnapshot(filename)
+	return s.Dt, s.T, s.Count, s.N, s.Gravity, s.Id, s.Pos, s.Vel
 }
 
 func (simulator *Simulator) Load(filename string) {
 	// HDF5 파일 읽기
-	dt, t, count, N, gravity, id, pos, vel := Read(filename)
-
-	simulator.Dt = dt
-	simulator.T = t
-	simulator.Count = count
-	simulator.N = N
-	simulator.Gravity = gravity
-
-	simulator.Id = id
-	simulator.Pos = pos
-	simulator.Vel = vel
+	s := ReadSnapshot(filename)
+
+	simulator.Dt = s.Dt
+	simulator.T = s.T
+	simulator.Count = s.Count
+	simulator.N = s.N
+	simulator.Gravity = s.Gravity
+
+	simulator.Id = s.Id
+	simulator.Species = s.Species
+	simulator.Mass = s.Mass
+	simulator.Charge = s.Charge
+	simulator.Pos = s.Pos
+	simulator.Vel = s.Vel
 }
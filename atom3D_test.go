@@ -0,0 +1,158 @@
+package atom3D
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// legacyNearAtoms는 셀마다 슬라이스를 두던 이전 GetNearAtoms입니다 (셀 목록 비교 기준).
+// 작은 주기 격자에서 감은 셀이 반복되면 처음 방문만 셉니다.
+func legacyNearAtoms(sim *Simulator, atom_index int, is_periodic bool) []int {
+	n := int(sim.RegionSize/sim.GridSize + 1)
+	grid := make([][]int, n*n*n)
+	for i := 0; i < sim.N; i++ {
+		x, y, z := sim.cellOf(i)
+		grid[x+y*n+z*n*n] = append(grid[x+y*n+z*n*n], i)
+	}
+
+	x, y, z := sim.cellOf(atom_index)
+	indices := []int{}
+	if !is_periodic {
+		for i := x - 1; i <= x+1; i++ {
+			for j := y - 1; j <= y+1; j++ {
+				for k := z - 1; k <= z+1; k++ {
+					if 0 <= i && i < n && 0 <= j && j < n && 0 <= k && k < n {
+						indices = append(indices, grid[i+j*n+k*n*n]...)
+					}
+				}
+			}
+		}
+	} else {
+		lo := func(c int) int {
+			if c == 0 {
+				return 2
+			}
+			return 1
+		}
+		hi := func(c int) int {
+			if c == n-2 {
+				return 2
+			}
+			return 1
+		}
+		seen := map[int]bool{}
+		for i := x - lo(x); i <= x+hi(x); i++ {
+			for j := y - lo(y); j <= y+hi(y); j++ {
+				for k := z - lo(z); k <= z+hi(z); k++ {
+					c := Mod(i, n) + Mod(j, n)*n + Mod(k, n)*n*n
+					if !seen[c] {
+						seen[c] = true
+						indices = append(indices, grid[c]...)
+					}
+				}
+			}
+		}
+	}
+	if at := slices.Index(indices, atom_index); at >= 0 {
+		indices = slices.Delete(indices, at, at+1)
+	}
+	return indices
+}
+
+// 평탄한 셀 목록의 GetNearAtoms / ForEachNeighbor는 이전 셀별 슬라이스 구현과 같은 순서의 이웃을 내야 합니다.
+func TestForEachNeighbor(t *testing.T) {
+	L := 10.
+	pos := randomPositions(600, L, 31)
+	pos = append(pos, Vector{-L / 2, -L / 2, -L / 2}, Vector{0.4999 * L, 0, -0.4999 * L})
+	for _, gridSize := range []float64{0.9, 2.6, 3.4, 6, 12} { // n = 12, 4, 3, 2, 1
+		sim := ewaldSimulator(pos, L)
+		sim.GridSize = gridSize
+		sim.MakeGrid()
+		if got := sim.CellStart[len(sim.CellStart)-1]; got != sim.N {
+			t.Fatalf("GridSize %.1f: cell list holds %d particles, want %d", gridSize, got, sim.N)
+		}
+		for _, periodic := range []bool{false, true} {
+			for i := 0; i < sim.N; i += 7 {
+				want := legacyNearAtoms(sim, i, periodic)
+				if got := sim.GetNearAtoms(i, periodic); !slices.Equal(got, want) {
+					t.Fatalf("GridSize %.1f, periodic %v: GetNearAtoms(%d) = %v, want %v", gridSize, periodic, i, got, want)
+				}
+				visited := []int{}
+				sim.ForEachNeighbor(i, periodic, func(j int) { visited = append(visited, j) })
+				if !slices.Equal(visited, want) {
+					t.Fatalf("GridSize %.1f, periodic %v: ForEachNeighbor(%d) visited %v, want %v", gridSize, periodic, i, visited, want)
+				}
+			}
+		}
+	}
+
+	// 방문자만 쓰는 이웃 순회는 할당이 없어야 합니다.
+	sim := ewaldSimulator(pos, L)
+	sim.GridSize = 0.9
+	sim.MakeGrid()
+	count := 0
+	if allocs := testing.AllocsPerRun(10, func() {
+		for i := 0; i < sim.N; i++ {
+			sim.ForEachNeighbor(i, true, func(j int) { count++ })
+		}
+	}); allocs != 0 {
+		t.Errorf("ForEachNeighbor: %.0f allocs per sweep, want 0", allocs)
+	}
+}
+
+// neighborSimulator는 셀당 평균 8개인 N = 10⁶ 균일 분포입니다.
+func neighborSimulator() *Simulator {
+	L := 100.
+	rng := rand.New(rand.NewSource(1))
+	pos := make([]Vector, 1000000)
+	for i := range pos {
+		pos[i] = Vector{(rng.Float64() - 0.5) * L, (rng.Float64() - 0.5) * L, (rng.Float64() - 0.5) * L}
+	}
+	sim := ewaldSimulator(pos, L)
+	sim.GridSize = 2
+	return sim
+}
+
+func BenchmarkMakeGrid(b *testing.B) {
+	sim := neighborSimulator()
+	sim.MakeGrid()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sim.MakeGrid()
+	}
+}
+
+func BenchmarkNeighbors(b *testing.B) {
+	sim := neighborSimulator()
+	sim.MakeGrid()
+	b.Run("GetNearAtoms", func(b *testing.B) {
+		b.ReportAllocs()
+		for it := 0; it < b.N; it++ {
+			count := 0
+			for i := 0; i < sim.N; i++ {
+				count += len(sim.GetNearAtoms(i, true))
+			}
+		}
+	})
+	b.Run("ForEachNeighbor", func(b *testing.B) {
+		b.ReportAllocs()
+		for it := 0; it < b.N; it++ {
+			count := 0
+			for i := 0; i < sim.N; i++ {
+				sim.ForEachNeighbor(i, true, func(j int) { count++ })
+			}
+		}
+	})
+	// 셀 순서(ParticleIndex)로 돌면 이웃 셀이 캐시에 남아 있습니다.
+	b.Run("ForEachNeighborCellOrder", func(b *testing.B) {
+		b.ReportAllocs()
+		for it := 0; it < b.N; it++ {
+			count := 0
+			for _, i := range sim.ParticleIndex {
+				sim.ForEachNeighbor(i, true, func(j int) { count++ })
+			}
+		}
+	})
+}
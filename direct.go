@@ -0,0 +1,148 @@
+package atom3D
+
+import "math"
+
+// ── DirectSum ────────────────────────────────────────────────────────────────
+
+// Softening은 직접합 중력의 근거리 연화 방식입니다.
+type Softening int
+
+const (
+	PlummerSoftening Softening = iota // Φ = -Gm/√(r²+ε²) (모든 거리에서 뉴턴 힘과 다름)
+	SplineSoftening                   // 3차 스플라인 커널, r >= ε 에서 정확한 뉴턴 힘 (Gadget 규약)
+)
+
+// DirectSum은 O(N²) 직접합 중력 ForceField입니다.
+// 작은 계의 실제 시뮬레이션과 P3M, BarnesHut 등 근사 솔버의 기준 해로 사용합니다.
+//
+// 파티클을 TileSize 크기 타일로 나누고 타일 쌍 (I, J), I <= J를 워커에 분배합니다.
+// 각 쌍은 한 번만 계산해 두 파티클에 반대 방향으로 더하므로 (뉴턴 제3법칙)
+// 운동량이 반올림 오차 안에서 보존되며, 워커별 누적 배열을 마지막에 합쳐 경쟁 상태가 없습니다.
+//
+// Periodic이면 sim.PeriodicDisplacement의 최소 이미지 규약을 씁니다. 이는 가장 가까운
+// 이미지만 더하는 근사이므로, 주기 박스의 정확한 기준 해는 EwaldSum을 사용하세요.
+type DirectSum struct {
+	G         float64   // 중력 상수
+	Epsilon   float64   // 연화 길이 (Plummer ε 또는 스플라인 커널 반경)
+	Softening Softening // 연화 방식 (기본값 PlummerSoftening)
+	Periodic  bool      // 주기 경계 최소 이미지 사용
+	TileSize  int       // 병렬 처리 타일 크기 (파티클 수)
+
+	Potential []float64 // 마지막 Accelerations 호출의 파티클별 퍼텐셜 (단위 질량당)
+}
+
+// NewDirectSum은 Plummer 연화와 타일 크기 64인 직접합 솔버를 생성합니다.
+func NewDirectSum(G, epsilon float64) *DirectSum {
+	return &DirectSum{
+		G:        G,
+		Epsilon:  epsilon,
+		TileSize: 64,
+	}
+}
+
+// kernel은 거리 r에서 단위 질량의 연화된 힘 계수 g(r)와 퍼텐셜 계수 φ(r)를 반환합니다.
+//
+//	a_i += G·m_j·g(r)·d,   Φ_i -= G·m_j·φ(r)   (d = r_j - r_i, 연화가 없으면 g = 1/r³, φ = 1/r)
+//
+// 스플라인은 Monaghan & Lattanzio (1985) 커널을 쓰는 Springel (2005) 식이며, u = r/ε 입니다.
+func (ds *DirectSum) kernel(r float64) (float64, float64) {
+	h := ds.Epsilon
+	if ds.Softening == PlummerSoftening {
+		inv := 1 / math.Sqrt(r*r+h*h)
+		return inv * inv * inv, inv
+	}
+	if r >= h {
+		return 1 / (r * r * r), 1 / r
+	}
+	u := r / h
+	u2 := u * u
+	if u < 0.5 {
+		g := 32./3. - 192./5.*u2 + 32*u2*u
+		phi := 14./5. - 16./3.*u2 + 48./5.*u2*u2 - 32./5.*u2*u2*u
+		return g / (h * h * h), phi / h
+	}
+	g := 64./3. - 48*u + 192./5.*u2 - 32./3.*u2*u - 1/(15*u2*u)
+	phi := 16./5. - 1/(15*u) - 32./3.*u2 + 16*u2*u - 48./5.*u2*u2 + 32./15.*u2*u2*u
+	return g / (h * h * h), phi / h
+}
+
+func (ds *DirectSum) displacement(sim *Simulator, i, j int) Vector {
+	if ds.Periodic {
+		return sim.PeriodicDisplacement(i, j)
+	}
+	return sim.Pos[j].Sub(sim.Pos[i])
+}
+
+// Compute는 가속도와 파티클별 퍼텐셜(단위 질량당, 자기 자신 제외)을 계산합니다.
+func (ds *DirectSum) Compute(sim *Simulator) ([]Vector, []float64) {
+	N := sim.N
+	tile := ds.TileSize
+	if tile < 1 {
+		tile = 64
+	}
+	nTiles := (N + tile - 1) / tile
+
+	pairs := make([][2]int, 0, nTiles*(nTiles+1)/2)
+	for I := 0; I < nTiles; I++ {
+		for J := I; J < nTiles; J++ {
+			pairs = append(pairs, [2]int{I, J})
+		}
+	}
+
+	numWorkers := sim.Parallel.workers()
+	accs := make([][]Vector, numWorkers)
+	pots := make([][]float64, numWorkers)
+	for w := 0; w < numWorkers; w++ {
+		accs[w] = make([]Vector, N)
+		pots[w] = make([]float64, N)
+	}
+	sim.Parallel.tasks().For(len(pairs), func(w, lo, hi int) {
+		acc, pot := accs[w], pots[w]
+		for _, pair := range pairs[lo:hi] {
+			iLo, iHi := pair[0]*tile, min((pair[0]+1)*tile, N)
+			jLo, jHi := pair[1]*tile, min((pair[1]+1)*tile, N)
+			for i := iLo; i < iHi; i++ {
+				start := jLo
+				if pair[0] == pair[1] {
+					start = i + 1 // 대각 타일은 j > i 쌍만
+				}
+				for j := start; j < jHi; j++ {
+					d := ds.displacement(sim, i, j)
+					g, phi := ds.kernel(d.Abs())
+					f := d.Mul(ds.G * g)
+					acc[i] = acc[i].Add(f.Mul(sim.Mass[j]))
+					acc[j] = acc[j].Sub(f.Mul(sim.Mass[i]))
+					pot[i] -= ds.G * sim.Mass[j] * phi
+					pot[j] -= ds.G * sim.Mass[i] * phi
+				}
+			}
+		}
+	})
+
+	acc := accs[0]
+	pot := pots[0]
+	for w := 1; w < numWorkers; w++ {
+		for i := 0; i < N; i++ {
+			acc[i] = acc[i].Add(accs[w][i])
+			pot[i] += pots[w][i]
+		}
+	}
+	return acc, pot
+}
+
+// Accelerations는 ForceField 인터페이스 구현입니다. Potential도 갱신합니다.
+func (ds *DirectSum) Accelerations(sim *Simulator) []Vector {
+	acc, pot := ds.Compute(sim)
+	ds.Potential = pot
+	return acc
+}
+
+// PotentialEnergy는 PotentialField 인터페이스 구현입니다: U = ½ Σ m_i Φ_i.
+func (ds *DirectSum) PotentialEnergy(sim *Simulator) float64 {
+	_, pot := ds.Compute(sim)
+	U := 0.0
+	for i, phi := range pot {
+		U += 0.5 * sim.Mass[i] * phi
+	}
+	return U
+}
@@ -0,0 +1,73 @@
+package atom3D
+
+import (
+	"math"
+	"testing"
+)
+
+// 직접합은 단순 이중 루프 기준(softenedDirect)과 같아야 하고, 타일 크기와 무관하며,
+// 쌍별 대칭 누적으로 운동량을 보존해야 합니다.
+func TestDirectSum(t *testing.T) {
+	sim := ewaldSimulator(plummerPositions(300, 1., 5), 0)
+	for i := range sim.Mass {
+		sim.Mass[i] = 1 + float64(i%4)
+	}
+	eps := 0.05
+
+	want, wantPot := softenedDirect(sim, 1., eps)
+	for _, tile := range []int{1, 7, 64, 1000} {
+		ds := NewDirectSum(1., eps)
+		ds.TileSize = tile
+		acc, pot := ds.Compute(sim)
+		if err := CompareForces(acc, want); err.Max > 1e-12 {
+			t.Errorf("tile %d: max error %.3e", tile, err.Max)
+		}
+		for i := range pot {
+			if math.Abs(pot[i]-wantPot[i]) > 1e-12*math.Abs(wantPot[i]) {
+				t.Fatalf("tile %d: particle %d: Φ = %v, want %v", tile, i, pot[i], wantPot[i])
+			}
+		}
+		var p Vector
+		for i, a := range acc {
+			p = p.Add(a.Mul(sim.Mass[i]))
+		}
+		if p.Abs() > 1e-9 {
+			t.Errorf("tile %d: total force %v, want 0", tile, p)
+		}
+	}
+
+	// 주기 최소 이미지: 반대쪽 면 근처의 두 파티클은 경계를 넘어 서로 끌어당깁니다.
+	pair := ewaldSimulator([]Vector{{-4.5, 0, 0}, {4.5, 0, 0}}, 10)
+	ds := NewDirectSum(1., 0)
+	ds.Periodic = true
+	if a := ds.Accelerations(pair); math.Abs(a[0].X+1) > 1e-12 || math.Abs(a[1].X-1) > 1e-12 {
+		t.Errorf("periodic pair: a = %v, want (-1, +1) along x", a)
+	}
+}
+
+// 스플라인 커널은 r >= ε에서 뉴턴 힘과 같고, 연속이며, g = -(dφ/dr)/r 를 만족해야 합니다.
+func TestSplineSoftening(t *testing.T) {
+	ds := NewDirectSum(1., 0.3)
+	ds.Softening = SplineSoftening
+	for _, r := range []float64{0.3, 0.45, 1.} {
+		if g, phi := ds.kernel(r); math.Abs(g*r*r*r-1) > 1e-12 || math.Abs(phi*r-1) > 1e-12 {
+			t.Errorf("r = %v >= ε: g·r³ = %v, φ·r = %v, want 1", r, g*r*r*r, phi*r)
+		}
+	}
+	for _, r := range []float64{0.15, 0.3} { // u = 1/2, 1 경계
+		g0, phi0 := ds.kernel(r * (1 - 1e-9))
+		g1, phi1 := ds.kernel(r * (1 + 1e-9))
+		if math.Abs(g0-g1) > 1e-6*g1 || math.Abs(phi0-phi1) > 1e-6*phi1 {
+			t.Errorf("kernel discontinuous at r = %v: g %v/%v, φ %v/%v", r, g0, g1, phi0, phi1)
+		}
+	}
+	h := 1e-6
+	for _, r := range []float64{0.02, 0.1, 0.2, 0.28} {
+		g, _ := ds.kernel(r)
+		_, pPlus := ds.kernel(r + h)
+		_, pMinus := ds.kernel(r - h)
+		if dphi := (pPlus - pMinus) / (2 * h); math.Abs(-dphi/r-g) > 1e-5*g {
+			t.Errorf("r = %v: -(dφ/dr)/r = %v, g = %v", r, -dphi/r, g)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package atom3D
+
+import "math"
+
+// EwaldSum은 주기 박스에서 1/r² 힘을 Ewald 합으로 정확하게 계산하는 기준 솔버입니다.
+// O(N²·이미지 수 + N·파수 수)로 느리므로 P3M 등 근사 솔버의 검증에 사용합니다.
+//
+//	실공간:  a_i += s Σ_j Σ_n m_j [erfc(αr) + (2αr/√π)·e^{-α²r²}] / r³ · d,   d = r_j - r_i + nL
+//	파수공간: a_i += s (4π/V) Σ_{k≠0} k·e^{-k²/4α²}/k² · Σ_j m_j sin(k·(r_j - r_i))
+//
+// k=0 항을 버리므로 P3M과 같은 균일 배경 규약을 따릅니다.
+// Coulomb 모드는 P3M과 같습니다 (소스 sim.Charge, s = -G, 가속도 = q_i/m_i · E_i).
+type EwaldSum struct {
+	L       float64 // 주기 박스 크기
+	G       float64 // 중력 상수 (Coulomb 모드에서는 쿨롱 상수 k_e)
+	Alpha   float64 // Ewald 분리 파라미터 (단위: 1/length)
+	Images  int     // 실공간 이미지 범위: n ∈ [-Images, Images]³
+	KMax    int     // 파수 범위: k = 2π/L·(nx, ny, nz),  |n_i| <= KMax
+	Coulomb bool    // true: 전하 기반 쿨롱 상호작용
+}
+
+// NewEwaldSum은 기계 정밀도(~1e-12)에 가까운 파라미터로 Ewald 합을 생성합니다.
+//
+//	αL = 3.5,  Images = 1 → 잘린 실공간 항 ~ erfc(1.5αL) ≈ 1e-13
+//	KMax = 8            → 잘린 파수공간 항 ~ exp(-(2π·8/L)²/4α²) ≈ 1e-14
+func NewEwaldSum(L, G float64) *EwaldSum {
+	return &EwaldSum{
+		L:      L,
+		G:      G,
+		Alpha:  3.5 / L,
+		Images: 1,
+		KMax:   8,
+	}
+}
+
+// NewCoulombEwaldSum은 쿨롱 상수 ke를 사용하는 전하 기반 Ewald 합을 생성합니다.
+func NewCoulombEwaldSum(L, ke float64) *EwaldSum {
+	e := NewEwaldSum(L, ke)
+	e.Coulomb = true
+	return e
+}
+
+func (e *EwaldSum) coupling() float64 {
+	if e.Coulomb {
+		return -e.G
+	}
+	return e.G
+}
+
+func (e *EwaldSum) sources(sim *Simulator) []float64 {
+	if e.Coulomb {
+		return sim.Charge
+	}
+	return sim.Mass
+}
+
+// Accelerations는 Ewald 합으로 각 파티클의 가속도를 계산합니다 (ForceField 구현).
+// 위치는 최소 이미지 규약으로 처리하므로 박스 밖 파티클도 허용됩니다.
+func (e *EwaldSum) Accelerations(sim *Simulator) []Vector {
+	N := sim.N
+	src := e.sources(sim)
+	s := e.coupling()
+	acc := make([]Vector, N)
+
+	// 파수공간: 구조 인자 C_k = Σ m_j cos(k·r_j), S_k = Σ m_j sin(k·r_j)
+	// sin(k·(r_j - r_i)) = S_k·cos(k·r_i) - C_k·sin(k·r_i).  k와 -k는 같은 기여이므로 반공간만 더하고 2배합니다.
+	dk := 2 * math.Pi / e.L
+	V := e.L * e.L * e.L
+	cosKR := make([]float64, N)
+	sinKR := make([]float64, N)
+	for nz := 0; nz <= e.KMax; nz++ {
+		for ny := -e.KMax; ny <= e.KMax; ny++ {
+			for nx := -e.KMax; nx <= e.KMax; nx++ {
+				if nz == 0 && (ny < 0 || (ny == 0 && nx <= 0)) {
+					continue
+				}
+				k := Vector{float64(nx) * dk, float64(ny) * dk, float64(nz) * dk}
+				k2 := k.Dot(k)
+				C, S := 0.0, 0.0
+				for j := 0; j < N; j++ {
+					sinKR[j], cosKR[j] = math.Sincos(k.Dot(sim.Pos[j]))
+					C += src[j] * cosKR[j]
+					S += src[j] * sinKR[j]
+				}
+				factor := 2 * s * 4 * math.Pi / V * math.Exp(-k2/(4*e.Alpha*e.Alpha)) / k2
+				for i := 0; i < N; i++ {
+					acc[i] = acc[i].Add(k.Mul(factor * (S*cosKR[i] - C*sinKR[i])))
+				}
+			}
+		}
+	}
+
+	// 실공간: 최소 이미지 변위에 이미지 격자를 더해 직접 합산 (파티클별 병렬)
+	sim.Parallel.For(N, func(_, lo, hi int) {
+		for i := lo; i < hi; i++ {
+			var a Vector
+			for j := 0; j < N; j++ {
+				d0 := minimumImage(sim.Pos[j].Sub(sim.Pos[i]), e.L)
+				for ix := -e.Images; ix <= e.Images; ix++ {
+					for iy := -e.Images; iy <= e.Images; iy++ {
+						for iz := -e.Images; iz <= e.Images; iz++ {
+							d := d0.Add(Vector{float64(ix) * e.L, float64(iy) * e.L, float64(iz) * e.L})
+							r := d.Abs()
+							if r == 0 {
+								continue
+							}
+							ar := e.Alpha * r
+							bracket := math.Erfc(ar) + 2*ar/math.Sqrt(math.Pi)*math.Exp(-ar*ar)
+							a = a.Add(d.Mul(s * src[j] * bracket / (r * r * r)))
+						}
+					}
+				}
+			}
+			acc[i] = acc[i].Add(a)
+		}
+	})
+
+	if e.Coulomb {
+		for i := range acc {
+			acc[i] = acc[i].Mul(sim.Charge[i] / sim.Mass[i])
+		}
+	}
+	return acc
+}
+
+// minimumImage는 변위 d를 각 축 [-L/2, L/2] 범위로 접습니다.
+func minimumImage(d Vector, L float64) Vector {
+	return Vector{
+		d.X - L*math.Round(d.X/L),
+		d.Y - L*math.Round(d.Y/L),
+		d.Z - L*math.Round(d.Z/L),
+	}
+}
+
+// ── 힘 오차 ──────────────────────────────────────────────────────────────────
+
+// ForceError는 근사 가속도와 기준 가속도의 비교 결과입니다.
+// 모든 오차는 기준 가속도의 RMS 크기 F_rms = √(Σ|a_i|²/N)로 정규화합니다.
+//
+//	PerParticle[i] = |a_i - a_ref,i| / F_rms
+//	RMS            = √(Σ|a_i - a_ref,i|² / Σ|a_ref,i|²)
+//	Max            = max_i PerParticle[i]
+type ForceError struct {
+	PerParticle []float64
+	RMS         float64
+	Max         float64
+}
+
+// CompareForces는 근사 가속도 approx를 기준 가속도 exact와 비교합니다.
+// 길이가 다르면 panic합니다.
+func CompareForces(approx, exact []Vector) ForceError {
+	if len(approx) != len(exact) {
+		panic("compare forces: length mismatch")
+	}
+	N := len(exact)
+	sumDiff, sumRef := 0.0, 0.0
+	diff := make([]float64, N)
+	for i := range exact {
+		diff[i] = approx[i].Sub(exact[i]).Abs()
+		sumDiff += diff[i] * diff[i]
+		sumRef += exact[i].Dot(exact[i])
+	}
+
+	result := ForceError{PerParticle: diff}
+	if sumRef == 0 {
+		// 기준 힘이 0(예: 완전 격자)이면 절대 오차를 그대로 보고합니다.
+		result.RMS = math.Sqrt(sumDiff / float64(N))
+		for _, d := range diff {
+			result.Max = math.Max(result.Max, d)
+		}
+		return result
+	}
+	fRMS := math.Sqrt(sumRef / float64(N))
+	for i := range diff {
+		diff[i] /= fRMS
+		result.Max = math.Max(result.Max, diff[i])
+	}
+	result.RMS = math.Sqrt(sumDiff / sumRef)
+	return result
+}
@@ -0,0 +1,103 @@
+package atom3D
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// ewaldSimulator는 박스 [-L/2, L/2)³ 안의 주어진 위치로 시뮬레이터를 만듭니다.
+func ewaldSimulator(pos []Vector, L float64) *Simulator {
+	sim := NewSimulator(0.01, make([]int, len(pos)), pos, make([]Vector, len(pos)), Vector{})
+	sim.RegionSize = L
+	return sim
+}
+
+func randomPositions(N int, L float64, seed int64) []Vector {
+	rng := rand.New(rand.NewSource(seed))
+	pos := make([]Vector, N)
+	for i := range pos {
+		pos[i] = Vector{(rng.Float64() - 0.5) * L, (rng.Float64() - 0.5) * L, (rng.Float64() - 0.5) * L}
+	}
+	return pos
+}
+
+// perturbedLattice는 n³ 단순입방 격자에서 각 점을 격자 간격의 amp배 이내로 흔듭니다.
+func perturbedLattice(n int, L, amp float64, seed int64) []Vector {
+	rng := rand.New(rand.NewSource(seed))
+	a := L / float64(n)
+	pos := make([]Vector, 0, n*n*n)
+	for z := 0; z < n; z++ {
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				pos = append(pos, Vector{
+					-L/2 + (float64(x)+0.5+amp*(rng.Float64()-0.5))*a,
+					-L/2 + (float64(y)+0.5+amp*(rng.Float64()-0.5))*a,
+					-L/2 + (float64(z)+0.5+amp*(rng.Float64()-0.5))*a,
+				})
+			}
+		}
+	}
+	return pos
+}
+
+// 정확한 Ewald 합은 분리 파라미터 α에 무관해야 합니다.
+func TestEwaldAlphaIndependence(t *testing.T) {
+	L := 10.
+	sim := ewaldSimulator(randomPositions(40, L, 3), L)
+	sim.Mass[0] = 3.
+
+	e1 := NewEwaldSum(L, 1.)
+	e2 := NewEwaldSum(L, 1.)
+	e2.Alpha = 5. / L
+	e2.KMax = 12
+
+	err := CompareForces(e1.Accelerations(sim), e2.Accelerations(sim))
+	if err.RMS > 1e-9 {
+		t.Errorf("alpha dependence: rms = %.3e, max = %.3e", err.RMS, err.Max)
+	}
+
+	// 완전 격자에서는 대칭에 의해 힘이 0입니다.
+	lattice := ewaldSimulator(perturbedLattice(4, L, 0, 1), L)
+	for i, a := range e1.Accelerations(lattice) {
+		if a.Abs() > 1e-10 {
+			t.Fatalf("lattice particle %d: a = %v, want 0", i, a)
+		}
+	}
+}
+
+// P3M(PM + erfc PP) 힘을 정확한 Ewald 합과 비교합니다.
+// PM 이산화 오차가 α·dx에 따라 커지므로 α·dx = 0.5, α·RCut = 3.2로 둡니다.
+func TestP3MEwaldError(t *testing.T) {
+	L := 10.
+	configs := []struct {
+		name string
+		pos  []Vector
+		tol  float64
+	}{
+		{"random", randomPositions(216, L, 7), 4e-2},
+		{"lattice", perturbedLattice(6, L, 0.5, 7), 1e-1}, // 힘이 작아 상대 오차가 큼
+	}
+	for _, c := range configs {
+		sim := ewaldSimulator(c.pos, L)
+		p3m := NewP3M(32, L, 1.)
+		p3m.Alpha = 0.5 / (L / 32)
+		p3m.RCut = 3.2 / p3m.Alpha
+		sim.GridSize = p3m.RCut
+
+		exact := NewEwaldSum(L, 1.).Accelerations(sim)
+		full := CompareForces(p3m.Accelerations(sim), exact)
+		pmOnly := CompareForces(p3m.PMForces(sim.Pos, sim.Mass), exact)
+		t.Logf("%s: P3M rms = %.3e max = %.3e, PM only rms = %.3e", c.name, full.RMS, full.Max, pmOnly.RMS)
+
+		if full.RMS > c.tol {
+			t.Errorf("%s: P3M rms force error %.3e > %.0e", c.name, full.RMS, c.tol)
+		}
+		if full.RMS > pmOnly.RMS {
+			t.Errorf("%s: PP correction increased the error (%.3e > %.3e)", c.name, full.RMS, pmOnly.RMS)
+		}
+		if math.IsNaN(full.Max) {
+			t.Errorf("%s: NaN force error", c.name)
+		}
+	}
+}
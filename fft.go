@@ -0,0 +1,172 @@
+package atom3D
+
+import (
+	"runtime"
+
+	"gonum.org/v1/gonum/dsp/fourier"
+)
+
+// ── 3D 실수 FFT 계획 ─────────────────────────────────────────────────────────
+
+// fftPlan은 ng×ng×ng 실수 격자의 재사용 가능한 3D real-to-complex FFT 계획입니다.
+//
+// 실수 장의 푸리에 변환은 F(-k) = F(k)* 이므로 x축 절반만 저장합니다 (half-complex 배치):
+//
+//	실공간:  field[ix + ng·iy + ng²·iz]                 (ng³)
+//	k-공간:  spec[kx + nh·iy + nh·ng·iz],  nh = ng/2+1   (nh·ng²),  kx = 0..ng/2
+//
+// x 방향은 실수 FFT, y·z 방향은 복소 FFT이며, 각 방향의 1D 변환들을 워커 goroutine에
+// 나눠 병렬로 수행합니다. 워커마다 gonum FFT 객체와 행 버퍼를 따로 가지므로
+// 계획을 만든 뒤에는 변환에서 추가 할당이 없습니다.
+// 한 계획을 여러 goroutine에서 동시에 사용하면 안 됩니다.
+type fftPlan struct {
+	ng, nh  int
+	workers int
+
+	real  []*fourier.FFT      // 워커별 x 방향 실수 FFT
+	cmplx []*fourier.CmplxFFT // 워커별 y, z 방향 복소 FFT
+	rows  [][]float64         // 워커별 실수 행 버퍼 [ng]
+	half  [][]complex128      // 워커별 half-complex 행 버퍼 [nh]
+	lines [][]complex128      // 워커별 복소 행 버퍼 [ng]
+
+	scratch []complex128 // inverse가 입력을 보존하기 위한 k-공간 작업 배열
+	pass    fftPass      // 진행 중인 1D 변환 단계 (병렬 루프 몸체)
+}
+
+// newFFTPlan은 격자 크기 ng의 FFT 계획을 만듭니다. workers <= 0 이면 runtime.NumCPU()를 사용합니다.
+func newFFTPlan(ng, workers int) *fftPlan {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	nh := ng/2 + 1
+	plan := &fftPlan{
+		ng:      ng,
+		nh:      nh,
+		workers: workers,
+		scratch: make([]complex128, nh*ng*ng),
+	}
+	for w := 0; w < workers; w++ {
+		plan.real = append(plan.real, fourier.NewFFT(ng))
+		plan.cmplx = append(plan.cmplx, fourier.NewCmplxFFT(ng))
+		plan.rows = append(plan.rows, make([]float64, ng))
+		plan.half = append(plan.half, make([]complex128, nh))
+		plan.lines = append(plan.lines, make([]complex128, ng))
+	}
+	return plan
+}
+
+// fftSize는 n 이상인 가장 작은 짝수 2·3·5-smooth 정수입니다.
+// gonum FFT는 큰 소인수가 있는 길이에서 느려지므로 패딩 격자 크기를 이 값으로 올립니다.
+func fftSize(n int) int {
+	for m := n + n%2; ; m += 2 {
+		k := m
+		for _, f := range [...]int{2, 3, 5} {
+			for k%f == 0 {
+				k /= f
+			}
+		}
+		if k == 1 {
+			return m
+		}
+	}
+}
+
+// specSize는 half-complex k-공간 배열의 길이 nh·ng² 입니다.
+func (f *fftPlan) specSize() int {
+	return f.nh * f.ng * f.ng
+}
+
+// parallel은 계획의 워커 수로 pass를 [0, n) 행에 병렬 실행합니다 (Parallel.run).
+func (f *fftPlan) parallel(n int) {
+	Parallel{Workers: f.workers}.run(n, &f.pass)
+}
+
+// forward는 실수 장 field [ng³]의 3D FFT를 spec [nh·ng²]에 씁니다 (비정규화).
+func (f *fftPlan) forward(field []float64, spec []complex128) {
+	ng, nh := f.ng, f.nh
+
+	// x 방향: 실수 → half-complex (ng² 행)
+	f.pass = fftPass{plan: f, kind: realForward, field: field, spec: spec}
+	f.parallel(ng * ng)
+	f.complexPass(spec, nh, nh*ng, false) // y 방향
+	f.complexPass(spec, nh*ng, nh, false) // z 방향
+}
+
+// inverse는 spec의 정규화된 3D 역FFT를 field [ng³]에 씁니다. spec은 바뀌지 않습니다.
+func (f *fftPlan) inverse(spec []complex128, field []float64) {
+	ng, nh := f.ng, f.nh
+	copy(f.scratch, spec)
+
+	f.complexPass(f.scratch, nh*ng, nh, true) // z 방향
+	f.complexPass(f.scratch, nh, nh*ng, true) // y 방향
+
+	// x 방향: half-complex → 실수, 1/ng³ 정규화 (gonum 역변환은 비정규화)
+	f.pass = fftPass{plan: f, kind: realInverse, field: field, spec: f.scratch}
+	f.parallel(ng * ng)
+}
+
+// complexPass는 보폭 stride인 축을 따라 모든 행에 복소 FFT를 적용합니다 (in-place).
+// 행은 (나머지 두 축) 인덱스로 구분되며, outer는 바깥 축의 보폭입니다.
+//
+//	y 방향: stride = nh,    outer = nh·ng  (행: kx, iz)
+//	z 방향: stride = nh·ng, outer = nh     (행: kx, iy)
+func (f *fftPlan) complexPass(spec []complex128, stride, outer int, inverse bool) {
+	f.pass = fftPass{plan: f, kind: complexLines, spec: spec, stride: stride, outer: outer, inverse: inverse}
+	f.parallel(f.nh * f.ng)
+}
+
+// fftPass는 forward, inverse, complexPass의 행별 1D 변환 병렬 루프입니다.
+type fftPass struct {
+	plan          *fftPlan
+	kind          int
+	field         []float64
+	spec          []complex128
+	stride, outer int
+	inverse       bool
+}
+
+const (
+	realForward  = iota // x 방향 실수 → half-complex
+	realInverse         // x 방향 half-complex → 실수, 1/ng³ 정규화
+	complexLines        // y 또는 z 방향 복소 FFT
+)
+
+func (p *fftPass) run(w, lo, hi int) {
+	f, field, spec := p.plan, p.field, p.spec
+	ng, nh := f.ng, f.nh
+	switch p.kind {
+	case realForward:
+		row, half := f.rows[w], f.half[w]
+		for line := lo; line < hi; line++ {
+			copy(row, field[line*ng:(line+1)*ng])
+			f.real[w].Coefficients(half, row)
+			copy(spec[line*nh:(line+1)*nh], half)
+		}
+	case realInverse:
+		row := f.rows[w]
+		scale := 1.0 / float64(ng*ng*ng)
+		for line := lo; line < hi; line++ {
+			f.real[w].Sequence(row, spec[line*nh:(line+1)*nh])
+			out := field[line*ng : (line+1)*ng]
+			for i, v := range row {
+				out[i] = v * scale
+			}
+		}
+	case complexLines:
+		line := f.lines[w]
+		for r := lo; r < hi; r++ {
+			base := r%nh + (r/nh)*p.outer
+			for i := 0; i < ng; i++ {
+				line[i] = spec[base+i*p.stride]
+			}
+			if p.inverse {
+				f.cmplx[w].Sequence(line, line)
+			} else {
+				f.cmplx[w].Coefficients(line, line)
+			}
+			for i := 0; i < ng; i++ {
+				spec[base+i*p.stride] = line[i]
+			}
+		}
+	}
+}
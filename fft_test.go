@@ -0,0 +1,82 @@
+package atom3D
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+// naiveDFT3D는 검증용 O(ng⁶) 3D DFT로, half-complex 배치(kx = 0..ng/2)만 반환합니다.
+func naiveDFT3D(field []float64, ng int) []complex128 {
+	nh := ng/2 + 1
+	spec := make([]complex128, nh*ng*ng)
+	for kz := 0; kz < ng; kz++ {
+		for ky := 0; ky < ng; ky++ {
+			for kx := 0; kx < nh; kx++ {
+				var sum complex128
+				for z := 0; z < ng; z++ {
+					for y := 0; y < ng; y++ {
+						for x := 0; x < ng; x++ {
+							phase := -2 * math.Pi * float64(kx*x+ky*y+kz*z) / float64(ng)
+							sum += complex(field[x+ng*(y+ng*z)], 0) * cmplx.Exp(complex(0, phase))
+						}
+					}
+				}
+				spec[kx+nh*(ky+ng*kz)] = sum
+			}
+		}
+	}
+	return spec
+}
+
+// FFT 계획의 순방향 변환은 직접 DFT와 같고, 역변환은 원래 장을 복원해야 합니다.
+// 홀수 격자와 워커 수가 행 수를 나누지 않는 경우도 확인합니다.
+func TestFFTPlan(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	for _, c := range []struct{ ng, workers int }{{8, 1}, {8, 3}, {6, 4}, {5, 0}} {
+		field := make([]float64, c.ng*c.ng*c.ng)
+		for i := range field {
+			field[i] = rng.Float64() - 0.5
+		}
+
+		plan := newFFTPlan(c.ng, c.workers)
+		spec := make([]complex128, plan.specSize())
+		plan.forward(field, spec)
+		for i, want := range naiveDFT3D(field, c.ng) {
+			if cmplx.Abs(spec[i]-want) > 1e-10 {
+				t.Fatalf("ng=%d workers=%d: spec[%d] = %v, want %v", c.ng, c.workers, i, spec[i], want)
+			}
+		}
+
+		before := append([]complex128(nil), spec...)
+		back := make([]float64, len(field))
+		plan.inverse(spec, back)
+		for i := range field {
+			if math.Abs(back[i]-field[i]) > 1e-12 {
+				t.Fatalf("ng=%d workers=%d: round trip [%d] = %g, want %g", c.ng, c.workers, i, back[i], field[i])
+			}
+		}
+		for i := range spec {
+			if spec[i] != before[i] {
+				t.Fatalf("ng=%d workers=%d: inverse modified its input", c.ng, c.workers)
+			}
+		}
+	}
+}
+
+func BenchmarkFFTPlan128(b *testing.B) {
+	ng := 128
+	plan := newFFTPlan(ng, 0)
+	field := make([]float64, ng*ng*ng)
+	for i := range field {
+		field[i] = float64(i % 7)
+	}
+	spec := make([]complex128, plan.specSize())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		plan.forward(field, spec)
+		plan.inverse(spec, field)
+	}
+}
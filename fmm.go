@@ -0,0 +1,457 @@
+package atom3D
+
+import "math"
+
+// ── FMM ──────────────────────────────────────────────────────────────────────
+
+// FMM은 데카르트 다중극 전개를 쓰는 O(N) 고속 다중극 방법(Fast Multipole Method) 중력 ForceField입니다.
+// 비주기(고립) 경계 전용이며, BarnesHut(O(N log N))보다 큰 계(N ≳ 10⁶)에 적합합니다.
+//
+// 다중극 차수 p (Order)에 대해 전체 차수 q = p+1 까지의 데카르트 Taylor 전개를 사용합니다
+// (Dehnen 2002 방식). 힘은 퍼텐셜의 기울기이므로 한 차수를 더 둬야 p차 다중극까지 힘에 반영됩니다.
+//
+//	P2M:  M_n  = Σ m·(-s)ⁿ/n!                               s = x - z_A
+//	M2M:  M_n += Σ_k M'_k·δ^{n-k}/(n-k)!                    δ = z_A - z_child
+//	M2L:  L_k += -G Σ_{|l| <= q-|k|} M_l·D_{k+l}(z_B - z_A)   D_n = ∂ⁿ(1/r)
+//	L2L:  L'_n = Σ_{k>=n} L_k·δ^{k-n}/(k-n)!                 δ = z_child - z_B
+//	L2P:  Φ = Σ_k L_k·tᵏ/k!,   a_i = -Σ_n L_{n+e_i}·tⁿ/n!     t = x - z_B
+//
+// 노드 쌍 (A, B)는 이중 트리 탐색(dual tree traversal)으로 정합니다.
+// r_A + r_B < Theta·|z_A - z_B| 이면 M2L, 둘 다 잎이면 P2P(직접합), 아니면 반지름이 큰 쪽을 나눕니다.
+// 전개 중심 z는 노드 질량중심, r은 중심에서 노드 파티클까지의 최대 거리입니다.
+//
+// 연화는 DirectSum의 3차 스플라인 커널(SplineSoftening)을 P2P에 적용합니다. 이 커널은
+// r >= ε 에서 정확한 뉴턴 힘이므로, 두 노드 사이 간격이 ε 이상일 때만 M2L을 쓰면
+// 전개가 연화된 직접합과 같은 힘을 근사합니다.
+//
+// 참고: Dehnen, J. Comput. Phys. 179, 27 (2002).
+type FMM struct {
+	G         float64 // 중력 상수
+	Order     int     // 다중극 차수 p (0: 단극, 클수록 정확, 비용 ~p⁶, 최대 fmmMaxOrder-1)
+	Theta     float64 // 분리 기준, 0 < Theta < 1 (보통 0.3–0.7)
+	Softening float64 // 스플라인 연화 길이 ε (r >= ε 에서 뉴턴 힘)
+	LeafSize  int     // 잎 노드의 최대 파티클 수
+
+	Potential []float64 // 마지막 Accelerations 호출의 파티클별 퍼텐셜 (단위 질량당)
+}
+
+// NewFMM은 차수 4, 잎 크기 16인 FMM 솔버를 생성합니다.
+// theta는 0 < theta < 1 이어야 합니다 (checkTheta).
+func NewFMM(G, theta, softening float64) *FMM {
+	checkTheta(theta)
+	return &FMM{
+		G:         G,
+		Order:     4,
+		Theta:     theta,
+		Softening: softening,
+		LeafSize:  16,
+	}
+}
+
+// checkTheta는 분리 기준이 0 < theta < 1 인지 확인하고, 아니면 패닉합니다.
+// theta >= 1 이면 r_A + r_B < theta·|z_A - z_B| 를 조상과 그 자손 노드 쌍도 만족할 수 있어
+// M2L이 노드 자신의 파티클을 다중극으로 다시 더하고, 전개도 수렴하지 않습니다.
+// theta <= 0 이면 M2L을 전혀 쓰지 않는 O(N²) 직접합이 됩니다.
+func checkTheta(theta float64) {
+	if !(theta > 0 && theta < 1) {
+		panic("fmm: theta must satisfy 0 < theta < 1")
+	}
+}
+
+// fmmTree는 한 번의 계산에 쓰는 트리와 노드별 다중극·국소 전개 계수입니다.
+type fmmTree struct {
+	*octree
+	idx    *multiIndex
+	radius []float64 // 노드 질량중심에서 파티클까지의 최대 거리
+	M, L   []float64 // 노드별 계수 [node·terms + n]
+
+	acc []Vector
+	pot []float64
+}
+
+// Compute는 가속도와 파티클별 퍼텐셜(단위 질량당, 자기 자신 제외)을 계산합니다.
+func (f *FMM) Compute(sim *Simulator) ([]Vector, []float64) {
+	checkTheta(f.Theta)
+	N := sim.N
+	acc := make([]Vector, N)
+	pot := make([]float64, N)
+	if N == 0 {
+		return acc, pot
+	}
+	order := min(max(f.Order, 0)+1, fmmMaxOrder) // 전개 전체 차수 q = p+1
+
+	tree := &fmmTree{
+		octree: buildOctree(sim.Pos, sim.Mass, f.LeafSize),
+		idx:    newMultiIndex(order),
+		acc:    acc,
+		pot:    pot,
+	}
+	terms := tree.idx.terms()
+	tree.radius = make([]float64, len(tree.nodes))
+	tree.M = make([]float64, len(tree.nodes)*terms)
+	tree.L = make([]float64, len(tree.nodes)*terms)
+	f.upward(tree, 0)
+
+	// 서로소인 부분 트리를 작업 단위로 나눠, 각 작업의 노드를 대상(target)으로 하는
+	// 탐색과 하향 전파(L2L, L2P)를 병렬로 수행합니다. 쓰기는 작업 부분 트리 안에서만 일어납니다.
+	numWorkers := sim.Parallel.workers()
+	tasks := fmmTasks(tree.octree, 4*numWorkers)
+	D := make([][]float64, numWorkers) // 워커별 M2L 도함수 작업 배열
+	for w := range D {
+		D[w] = make([]float64, tree.idx.terms())
+	}
+	sim.Parallel.tasks().For(len(tasks), func(w, lo, hi int) {
+		for _, task := range tasks[lo:hi] {
+			f.interact(tree, task, 0, D[w])
+			f.downward(tree, task)
+		}
+	})
+
+	return acc, pot
+}
+
+// fmmTasks는 루트에서 시작해 노드 수가 target 이상이 될 때까지 내부 노드를 자식으로 바꿔
+// 모든 파티클을 덮는 서로소 부분 트리 목록을 반환합니다.
+func fmmTasks(tree *octree, target int) []int {
+	frontier := []int{0}
+	for len(frontier) < target {
+		next := make([]int, 0, 8*len(frontier))
+		split := false
+		for _, id := range frontier {
+			if tree.nodes[id].leaf {
+				next = append(next, id)
+				continue
+			}
+			for _, c := range tree.nodes[id].child {
+				if c >= 0 {
+					next = append(next, c)
+				}
+			}
+			split = true
+		}
+		frontier = next
+		if !split {
+			break
+		}
+	}
+	return frontier
+}
+
+// upward는 P2M(잎)과 M2M(내부 노드)으로 다중극 계수와 노드 반지름을 계산합니다.
+func (f *FMM) upward(tree *fmmTree, id int) {
+	node := &tree.nodes[id]
+	M := tree.moments(id)
+	mi := tree.idx
+
+	if node.leaf {
+		for _, j := range tree.index[node.first : node.first+node.count] {
+			s := tree.pos[j].Sub(node.com)
+			tree.radius[id] = math.Max(tree.radius[id], s.Abs())
+			pw := mi.powers(s.Mul(-1))
+			for n := range M {
+				M[n] += tree.mass[j] * mi.monomial(&pw, n) * mi.invFact[n]
+			}
+		}
+		return
+	}
+
+	for _, c := range node.child {
+		if c < 0 {
+			continue
+		}
+		f.upward(tree, c)
+		child := &tree.nodes[c]
+		delta := node.com.Sub(child.com)
+		tree.radius[id] = math.Max(tree.radius[id], tree.radius[c]+delta.Abs())
+
+		Mc := tree.moments(c)
+		pw := mi.powers(delta)
+		for n := range M {
+			for k := range Mc {
+				if d := mi.diff(n, k); d >= 0 {
+					M[n] += Mc[k] * mi.monomial(&pw, d) * mi.invFact[d]
+				}
+			}
+		}
+	}
+}
+
+// interact는 대상 노드 t가 소스 노드 s로부터 받는 기여를 이중 트리 탐색으로 누적합니다.
+// D는 워커가 재사용하는 M2L 작업 배열입니다.
+func (f *FMM) interact(tree *fmmTree, t, s int, D []float64) {
+	T, S := &tree.nodes[t], &tree.nodes[s]
+
+	if t == s {
+		if T.leaf {
+			f.p2p(tree, t, s)
+			return
+		}
+		for _, a := range T.child {
+			for _, b := range T.child {
+				if a >= 0 && b >= 0 {
+					f.interact(tree, a, b, D)
+				}
+			}
+		}
+		return
+	}
+
+	R := T.com.Sub(S.com)
+	dist, extent := R.Abs(), tree.radius[t]+tree.radius[s]
+	if extent < f.Theta*dist && dist-extent >= f.Softening {
+		f.m2l(tree, t, s, R, D)
+		return
+	}
+	if T.leaf && S.leaf {
+		f.p2p(tree, t, s)
+		return
+	}
+
+	if S.leaf || (!T.leaf && tree.radius[t] > tree.radius[s]) {
+		for _, c := range T.child {
+			if c >= 0 {
+				f.interact(tree, c, s, D)
+			}
+		}
+	} else {
+		for _, c := range S.child {
+			if c >= 0 {
+				f.interact(tree, t, c, D)
+			}
+		}
+	}
+}
+
+// p2p는 소스 잎 s의 파티클이 대상 잎 t의 파티클에 주는 연화 직접합입니다.
+func (f *FMM) p2p(tree *fmmTree, t, s int) {
+	T, S := &tree.nodes[t], &tree.nodes[s]
+	soft := DirectSum{Epsilon: f.Softening, Softening: SplineSoftening}
+	for _, i := range tree.index[T.first : T.first+T.count] {
+		x := tree.pos[i]
+		for _, j := range tree.index[S.first : S.first+S.count] {
+			if j == i {
+				continue
+			}
+			d := tree.pos[j].Sub(x)
+			g, phi := soft.kernel(d.Abs())
+			tree.acc[i] = tree.acc[i].Add(d.Mul(f.G * tree.mass[j] * g))
+			tree.pot[i] -= f.G * tree.mass[j] * phi
+		}
+	}
+}
+
+// m2l은 소스 s의 다중극을 대상 t의 국소 전개로 옮깁니다 (R = z_t - z_s).
+func (f *FMM) m2l(tree *fmmTree, t, s int, R Vector, D []float64) {
+	mi := tree.idx
+	mi.derivatives(R, D)
+	M, L := tree.moments(s), tree.locals(t)
+	for k := range L {
+		sum := 0.0
+		for l := range M {
+			if kl := mi.sum(k, l); kl >= 0 {
+				sum += M[l] * D[kl]
+			}
+		}
+		L[k] -= f.G * sum
+	}
+}
+
+// downward는 L2L로 국소 전개를 자식에 내려보내고 잎에서 L2P로 파티클에 더합니다.
+func (f *FMM) downward(tree *fmmTree, id int) {
+	node := &tree.nodes[id]
+	mi := tree.idx
+	L := tree.locals(id)
+
+	if node.leaf {
+		for _, i := range tree.index[node.first : node.first+node.count] {
+			pw := mi.powers(tree.pos[i].Sub(node.com))
+			var a Vector
+			for n := range L {
+				w := mi.monomial(&pw, n) * mi.invFact[n]
+				tree.pot[i] += L[n] * w
+				a.X -= mi.shifted(L, n, 0) * w
+				a.Y -= mi.shifted(L, n, 1) * w
+				a.Z -= mi.shifted(L, n, 2) * w
+			}
+			tree.acc[i] = tree.acc[i].Add(a)
+		}
+		return
+	}
+
+	for _, c := range node.child {
+		if c < 0 {
+			continue
+		}
+		Lc := tree.locals(c)
+		pw := mi.powers(tree.nodes[c].com.Sub(node.com))
+		for n := range Lc {
+			for k := range L {
+				if d := mi.diff(k, n); d >= 0 {
+					Lc[n] += L[k] * mi.monomial(&pw, d) * mi.invFact[d]
+				}
+			}
+		}
+		f.downward(tree, c)
+	}
+}
+
+func (t *fmmTree) moments(id int) []float64 {
+	n := t.idx.terms()
+	return t.M[id*n : (id+1)*n]
+}
+
+func (t *fmmTree) locals(id int) []float64 {
+	n := t.idx.terms()
+	return t.L[id*n : (id+1)*n]
+}
+
+// Accelerations는 ForceField 인터페이스 구현입니다. Potential도 갱신합니다.
+func (f *FMM) Accelerations(sim *Simulator) []Vector {
+	acc, pot := f.Compute(sim)
+	f.Potential = pot
+	return acc
+}
+
+// PotentialEnergy는 PotentialField 인터페이스 구현입니다: U = ½ Σ m_i Φ_i.
+func (f *FMM) PotentialEnergy(sim *Simulator) float64 {
+	_, pot := f.Compute(sim)
+	U := 0.0
+	for i, phi := range pot {
+		U += 0.5 * sim.Mass[i] * phi
+	}
+	return U
+}
+
+// ── 다중 지표 ────────────────────────────────────────────────────────────────
+
+// multiIndex는 |n| = n_x + n_y + n_z <= p 인 다중 지표 n의 목록과 연산 표입니다.
+// 목록은 |n| 오름차순이므로 점화식이 앞선 항만 참조합니다.
+type multiIndex struct {
+	p       int
+	n       [][3]int  // 목록 번호 → 다중 지표
+	invFact []float64 // 1/n! = 1/(n_x!·n_y!·n_z!)
+	lookup  []int     // (p+1)³ 배열 → 목록 번호 (|n| > p 이면 -1)
+	sumTab  []int     // [k·terms + l] → k+l 의 목록 번호 (-1: 차수 초과)
+	diffTab []int     // [n·terms + k] → n-k 의 목록 번호 (-1: 음수 성분)
+}
+
+func newMultiIndex(p int) *multiIndex {
+	mi := &multiIndex{p: p, lookup: make([]int, (p+1)*(p+1)*(p+1))}
+	for i := range mi.lookup {
+		mi.lookup[i] = -1
+	}
+	for order := 0; order <= p; order++ {
+		for nx := order; nx >= 0; nx-- {
+			for ny := order - nx; ny >= 0; ny-- {
+				nz := order - nx - ny
+				mi.lookup[nx+(p+1)*(ny+(p+1)*nz)] = len(mi.n)
+				mi.n = append(mi.n, [3]int{nx, ny, nz})
+				mi.invFact = append(mi.invFact, 1/(factorial(nx)*factorial(ny)*factorial(nz)))
+			}
+		}
+	}
+
+	terms := mi.terms()
+	mi.sumTab = make([]int, terms*terms)
+	mi.diffTab = make([]int, terms*terms)
+	for a, na := range mi.n {
+		for b, nb := range mi.n {
+			mi.sumTab[a*terms+b] = mi.at(na[0]+nb[0], na[1]+nb[1], na[2]+nb[2])
+			mi.diffTab[a*terms+b] = mi.at(na[0]-nb[0], na[1]-nb[1], na[2]-nb[2])
+		}
+	}
+	return mi
+}
+
+func factorial(n int) float64 {
+	f := 1.0
+	for i := 2; i <= n; i++ {
+		f *= float64(i)
+	}
+	return f
+}
+
+// terms는 계수 개수 (p+1)(p+2)(p+3)/6 입니다.
+func (mi *multiIndex) terms() int {
+	return len(mi.n)
+}
+
+// at은 다중 지표 (nx, ny, nz)의 목록 번호입니다 (음수 성분이나 |n| > p 이면 -1).
+func (mi *multiIndex) at(nx, ny, nz int) int {
+	if nx < 0 || ny < 0 || nz < 0 || nx+ny+nz > mi.p {
+		return -1
+	}
+	return mi.lookup[nx+(mi.p+1)*(ny+(mi.p+1)*nz)]
+}
+
+func (mi *multiIndex) sum(k, l int) int {
+	return mi.sumTab[k*mi.terms()+l]
+}
+
+func (mi *multiIndex) diff(n, k int) int {
+	return mi.diffTab[n*mi.terms()+k]
+}
+
+// shifted는 L_{n+e_axis} 를 반환합니다 (차수를 넘으면 0).
+func (mi *multiIndex) shifted(L []float64, n, axis int) float64 {
+	e := mi.n[n]
+	e[axis]++
+	if k := mi.at(e[0], e[1], e[2]); k >= 0 {
+		return L[k]
+	}
+	return 0
+}
+
+// fmmMaxOrder는 FMM 전개 차수의 상한입니다 (비용이 ~p⁶ 이므로 실용 범위를 충분히 넘음).
+const fmmMaxOrder = 12
+
+// powerTable은 벡터 성분별 거듭제곱 표 [성분][지수] 입니다.
+type powerTable [3][fmmMaxOrder + 1]float64
+
+// powers는 v의 성분별 거듭제곱 v_c^e (e = 0..p) 입니다.
+func (mi *multiIndex) powers(v Vector) powerTable {
+	var pw powerTable
+	for c, x := range [3]float64{v.X, v.Y, v.Z} {
+		pw[c][0] = 1
+		for e := 1; e <= mi.p; e++ {
+			pw[c][e] = pw[c][e-1] * x
+		}
+	}
+	return pw
+}
+
+// monomial은 vⁿ = v_x^{n_x}·v_y^{n_y}·v_z^{n_z} 입니다 (pw = powers(v)).
+func (mi *multiIndex) monomial(pw *powerTable, n int) float64 {
+	e := mi.n[n]
+	return pw[0][e[0]] * pw[1][e[1]] * pw[2][e[2]]
+}
+
+// derivatives는 R에서 1/r의 도함수 D_n = ∂ⁿ(1/r), |n| <= p 를 T (길이 terms)에 씁니다.
+// Taylor 계수 T_n = D_n/n! 의 점화식 (Duan & Krasny 2001)을 씁니다:
+//
+//	|n|·r²·T_n = -(2|n|-1) Σ_i R_i·T_{n-e_i} - (|n|-1) Σ_i T_{n-2e_i}
+func (mi *multiIndex) derivatives(R Vector, T []float64) {
+	r2 := R.Dot(R)
+	comp := [3]float64{R.X, R.Y, R.Z}
+	T[0] = 1 / math.Sqrt(r2)
+	for n := 1; n < len(T); n++ {
+		e := mi.n[n]
+		order := float64(e[0] + e[1] + e[2])
+		first, second := 0.0, 0.0
+		for c := 0; c < 3; c++ {
+			m := e
+			m[c]--
+			if k := mi.at(m[0], m[1], m[2]); k >= 0 {
+				first += comp[c] * T[k]
+			}
+			m[c]--
+			if k := mi.at(m[0], m[1], m[2]); k >= 0 {
+				second += T[k]
+			}
+		}
+		T[n] = -((2*order-1)*first + (order-1)*second) / (order * r2)
+	}
+	for n := range T {
+		T[n] /= mi.invFact[n] // D_n = n!·T_n
+	}
+}
@@ -0,0 +1,86 @@
+package atom3D
+
+import (
+	"math"
+	"testing"
+)
+
+// 1/r 도함수 점화식을 수치 미분과 비교합니다.
+func TestFMMDerivatives(t *testing.T) {
+	mi := newMultiIndex(4)
+	R := Vector{0.7, -1.1, 0.4}
+	D := make([]float64, mi.terms())
+	mi.derivatives(R, D)
+
+	inv := func(v Vector) float64 { return 1 / v.Abs() }
+	h := 1e-4
+	axes := [3]Vector{{h, 0, 0}, {0, h, 0}, {0, 0, h}}
+	// ∂_x∂_y(1/r) 와 ∂_z³(1/r) 의 중앙 차분
+	dxdy := (inv(R.Add(axes[0]).Add(axes[1])) - inv(R.Add(axes[0]).Sub(axes[1])) -
+		inv(R.Sub(axes[0]).Add(axes[1])) + inv(R.Sub(axes[0]).Sub(axes[1]))) / (4 * h * h)
+	dz3 := (inv(R.Add(axes[2].Mul(2))) - 2*inv(R.Add(axes[2])) + 2*inv(R.Sub(axes[2])) - inv(R.Sub(axes[2].Mul(2)))) / (2 * h * h * h)
+	for _, c := range []struct {
+		n    [3]int
+		want float64
+	}{{[3]int{1, 1, 0}, dxdy}, {[3]int{0, 0, 3}, dz3}} {
+		got := D[mi.at(c.n[0], c.n[1], c.n[2])]
+		if math.Abs(got-c.want) > 1e-4*math.Abs(c.want) {
+			t.Errorf("D%v = %v, numerical %v", c.n, got, c.want)
+		}
+	}
+}
+
+// 분리 기준은 0 < Theta < 1 이어야 합니다. 생성 후 바꾼 Theta도 Compute가 거부합니다.
+func TestFMMTheta(t *testing.T) {
+	panics := func(f func()) (panicked bool) {
+		defer func() { panicked = recover() != nil }()
+		f()
+		return false
+	}
+	for _, theta := range []float64{0, -0.5, 1, 1.5, math.NaN()} {
+		if !panics(func() { NewFMM(1., theta, 0.01) }) {
+			t.Errorf("NewFMM accepted theta = %v", theta)
+		}
+	}
+
+	sim := ewaldSimulator(plummerPositions(200, 1., 9), 0)
+	fmm := NewFMM(1., 0.5, 0.01)
+	fmm.Compute(sim)
+	fmm.Theta = 1.2
+	if !panics(func() { fmm.Compute(sim) }) {
+		t.Error("Compute accepted theta = 1.2")
+	}
+}
+
+// FMM 힘과 퍼텐셜을 직접합과 비교합니다. 차수가 높을수록 오차가 줄어야 합니다.
+func TestFMM(t *testing.T) {
+	sim := ewaldSimulator(plummerPositions(2000, 1., 7), 0)
+	for i := range sim.Mass {
+		sim.Mass[i] = 1 + float64(i%3)
+	}
+	eps := 0.01
+	direct := NewDirectSum(1., eps)
+	direct.Softening = SplineSoftening
+	exact, exactPot := direct.Compute(sim)
+
+	prev := math.Inf(1)
+	for _, order := range []int{0, 2, 4, 6} {
+		fmm := NewFMM(1., 0.5, eps)
+		fmm.Order = order
+		err := CompareForces(fmm.Accelerations(sim), exact)
+
+		U, want := 0., 0.
+		for i := range fmm.Potential {
+			U += 0.5 * sim.Mass[i] * fmm.Potential[i]
+			want += 0.5 * sim.Mass[i] * exactPot[i]
+		}
+		t.Logf("order %d: rms = %.3e, max = %.3e, ΔU/U = %.1e", order, err.RMS, err.Max, math.Abs(U/want-1))
+		if err.RMS > prev {
+			t.Errorf("order %d: rms %.3e did not decrease from %.3e", order, err.RMS, prev)
+		}
+		prev = err.RMS
+	}
+	if prev > 1e-4 {
+		t.Errorf("order 6: rms force error %.3e > 1e-4", prev)
+	}
+}
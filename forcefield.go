@@ -0,0 +1,98 @@
+package atom3D
+
+// ForceField는 시뮬레이터의 각 파티클에 작용하는 가속도를 계산하는 힘장입니다.
+//
+// Simulator.AddForceField로 등록된 힘장들은 Simulator.Accelerations에서
+// 모두 합산되어 Step의 적분 루프에 사용됩니다.
+//
+//	Accelerations(sim) : 길이 sim.N의 가속도 배열 (sim.Pos 기준)
+type ForceField interface {
+	Accelerations(sim *Simulator) []Vector
+}
+
+// PotentialField는 퍼텐셜 에너지를 함께 계산할 수 있는 ForceField입니다.
+// 에너지 보존 진단(Simulator.PotentialEnergy)에 사용됩니다.
+type PotentialField interface {
+	ForceField
+	PotentialEnergy(sim *Simulator) float64
+}
+
+// AccelerationAdder는 가속도를 새 배열 없이 acc에 더할 수 있는 ForceField입니다.
+// Simulator.AccelerationsInto(적분기의 스텝 루프)는 이 메서드를 우선 사용하므로,
+// 내부 작업 버퍼를 재사용하는 힘장(P3M 등)은 정상 상태 스텝에서 배열을 새로 할당하지 않습니다.
+//
+//	AddAccelerations(sim, acc) : acc[i] += a_i  (len(acc) == sim.N)
+type AccelerationAdder interface {
+	ForceField
+	AddAccelerations(sim *Simulator, acc []Vector)
+}
+
+// UniformGravity는 모든 파티클에 같은 가속도 G를 주는 균일 중력장입니다.
+// Simulator.Gravity는 내부적으로 이 힘장으로 처리됩니다.
+type UniformGravity struct {
+	G Vector // 중력 가속도
+}
+
+func (field UniformGravity) Accelerations(sim *Simulator) []Vector {
+	acc := make([]Vector, sim.N)
+	for i := range acc {
+		acc[i] = field.G
+	}
+	return acc
+}
+
+func (field UniformGravity) AddAccelerations(sim *Simulator, acc []Vector) {
+	for i := range acc {
+		acc[i] = acc[i].Add(field.G)
+	}
+}
+
+// PotentialEnergy는 U = -Σ m·G·x 를 반환합니다.
+func (field UniformGravity) PotentialEnergy(sim *Simulator) float64 {
+	U := 0.0
+	for i := 0; i < sim.N; i++ {
+		U -= sim.Mass[i] * field.G.Dot(sim.Pos[i])
+	}
+	return U
+}
+
+// AddForceField는 힘장을 시뮬레이터에 등록합니다.
+func (simulator *Simulator) AddForceField(field ForceField) {
+	simulator.ForceFields = append(simulator.ForceFields, field)
+	simulator.fieldsVersion++
+}
+
+// Accelerations는 Gravity와 등록된 모든 힘장의 가속도 합을 새 배열로 반환합니다.
+func (simulator *Simulator) Accelerations() []Vector {
+	return simulator.AccelerationsInto(nil)
+}
+
+// AccelerationsInto는 Accelerations와 같은 합을 acc에 써서 반환합니다.
+// acc의 용량이 sim.N보다 작으면 새로 할당합니다. AccelerationAdder를 구현한 힘장은
+// acc에 직접 더하고, 나머지는 Accelerations의 결과를 더합니다.
+func (simulator *Simulator) AccelerationsInto(acc []Vector) []Vector {
+	acc = resize(acc, simulator.N)
+	clear(acc)
+	UniformGravity{G: simulator.Gravity}.AddAccelerations(simulator, acc)
+	for _, field := range simulator.ForceFields {
+		if adder, ok := field.(AccelerationAdder); ok {
+			adder.AddAccelerations(simulator, acc)
+			continue
+		}
+		simulator.loops.axpy = axpyLoop{acc, field.Accelerations(simulator), 1}
+		simulator.Parallel.run(simulator.N, &simulator.loops.axpy)
+	}
+	return acc
+}
+
+// PotentialEnergy는 Gravity와 PotentialField를 구현한 힘장들의
+// 퍼텐셜 에너지 합을 반환합니다. 에너지를 계산하지 않는 힘장은 무시됩니다.
+func (simulator *Simulator) PotentialEnergy() float64 {
+	U := UniformGravity{G: simulator.Gravity}.PotentialEnergy(simulator)
+	for _, field := range simulator.ForceFields {
+		if pf, ok := field.(PotentialField); ok {
+			U += pf.PotentialEnergy(simulator)
+		}
+	}
+	return U
+}
@@ -0,0 +1,30 @@
+package atom3D
+
+import (
+	"math"
+	"testing"
+)
+
+func TestForceFieldSum(t *testing.T) {
+	pos := []Vector{{0, 0, 0}, {1, 2, 3}}
+	vel := []Vector{{0, 0, 0}, {0, 0, 0}}
+	sim := NewSimulator(0.1, []int{0, 0}, pos, vel, Vector{0, 0, -1})
+	sim.AddForceField(UniformGravity{G: Vector{1, 0, 0}})
+
+	acc := sim.Accelerations()
+	for i, a := range acc {
+		if a != (Vector{1, 0, -1}) {
+			t.Errorf("acc[%d] = %v, want {1 0 -1}", i, a)
+		}
+	}
+
+	// U = -Σ g·x, g = (1, 0, -1)
+	if U := sim.PotentialEnergy(); math.Abs(U-2) > 1e-12 {
+		t.Errorf("PotentialEnergy = %v, want 2", U)
+	}
+
+	sim.Step()
+	if sim.Vel[0] != (Vector{0.1, 0, -0.1}) {
+		t.Errorf("Vel[0] = %v after Step", sim.Vel[0])
+	}
+}
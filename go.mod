@@ -5,11 +5,11 @@ go 1.24.0
 require (
 	github.com/flopp/go-findfont v0.1.0
 	github.com/fogleman/gg v1.3.0
+	gonum.org/v1/gonum v0.17.0
 	gonum.org/v1/hdf5 v0.0.0-20210714002203-8c5d23bc6946
 )
 
 require (
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	golang.org/x/image v0.25.0 // indirect
-	gonum.org/v1/gonum v0.17.0 // indirect
 )
@@ -28,6 +28,13 @@ func CreateAttributeVector(group *hdf5.Group, name string, value Vector) {
 	attr.Close()
 }
 
+func CreateAttributeString(group *hdf5.Group, name string, value string) {
+	space, _ := hdf5.CreateSimpleDataspace([]uint{1}, nil)
+	attr, _ := group.CreateAttribute(name, hdf5.T_GO_STRING, space)
+	attr.Write(&value, hdf5.T_GO_STRING)
+	attr.Close()
+}
+
 func CreateDatasetFloat(group *hdf5.Group, name string, data []float64, dims []uint) {
 	space, _ := hdf5.CreateSimpleDataspace(dims, nil)
 	dataset, _ := group.CreateDataset(name, hdf5.T_NATIVE_DOUBLE, space)
@@ -66,12 +73,25 @@ func ReadAttributeVector(group *hdf5.Group, name string) Vector {
 	return value
 }
 
+func ReadAttributeString(group *hdf5.Group, name string) string {
+	attr, _ := group.OpenAttribute(name)
+	var value string
+	attr.Read(&value, hdf5.T_GO_STRING)
+	attr.Close()
+	return value
+}
+
 func ReadDatasetFloat(group *hdf5.Group, name string) []float64 {
 	dataset, _ := group.OpenDataset(name)
 	defer dataset.Close()
 	dataspace := dataset.Space()
 	dims, _, _ := dataspace.SimpleExtentDims()
-	data := make([]float64, dims[0])
+	// 다차원 데이터셋은 flat array로 읽음
+	size := uint(1)
+	for _, d := range dims {
+		size *= d
+	}
+	data := make([]float64, size)
 	dataset.Read(&data)
 	return data
 }
@@ -0,0 +1,362 @@
+package atom3D
+
+import (
+	"math"
+	"slices"
+)
+
+// Integrator는 Simulator.Step에서 사용하는 시간 적분기입니다.
+//
+// Step(sim)은 sim.Dt 만큼 sim.Pos, sim.Vel을 갱신합니다.
+// (Count, T 갱신은 Simulator.Step이 담당하며, T는 적분 전에 스텝 끝 시각으로 바뀝니다.)
+//
+// 가속도는 Simulator.AccelerationsInto로 구현체의 내부 버퍼에 계산하므로 Gravity와 등록된
+// 모든 ForceField가 적용되고, 정상 상태 스텝은 (워커 하나에서) 아무것도 할당하지 않습니다.
+// 계산하는 동안 sim.T는 그 단계의 시각(스텝 시작 T - Dt, 중간 단계, 끝)으로 맞춰지므로
+// 시간에 의존하는 힘장은 sim.T를 읽으면 됩니다.
+//
+// 구현체는 스텝마다 재사용하는 내부 버퍼를 가지므로 포인터로 설정합니다:
+//
+//	sim.Integrator = &atom3D.LeapfrogKDK{}
+type Integrator interface {
+	Step(sim *Simulator)
+}
+
+// ── 내부 헬퍼 ────────────────────────────────────────────────────────────────
+
+// accelerationsAt은 위치 pos, 시각 t에서의 가속도를 acc 버퍼에 계산합니다.
+// sim.Pos, sim.T를 잠시 pos, t로 바꿔 AccelerationsInto를 호출한 뒤 되돌립니다.
+func accelerationsAt(sim *Simulator, pos []Vector, t float64, acc []Vector) []Vector {
+	origPos, origT := sim.Pos, sim.T
+	sim.Pos, sim.T = pos, t
+	acc = sim.AccelerationsInto(acc)
+	sim.Pos, sim.T = origPos, origT
+	return acc
+}
+
+// stepStart는 진행 중인 스텝의 시작 시각입니다.
+// Simulator.Step이 적분 전에 T를 스텝 끝 시각으로 갱신하므로 T - Dt 입니다.
+func stepStart(sim *Simulator) float64 {
+	return sim.T - sim.Dt
+}
+
+// resize는 buf를 길이 n으로 재사용하거나 새로 할당합니다 (내용은 정의되지 않음).
+func resize[T any](buf []T, n int) []T {
+	if cap(buf) < n {
+		return make([]T, n)
+	}
+	return buf[:n]
+}
+
+func drift(sim *Simulator, dt float64) {
+	sim.loops.axpy = axpyLoop{sim.Pos, sim.Vel, dt}
+	sim.Parallel.run(sim.N, &sim.loops.axpy)
+}
+
+func kick(sim *Simulator, acc []Vector, dt float64) {
+	sim.loops.axpy = axpyLoop{sim.Vel, acc, dt}
+	sim.Parallel.run(sim.N, &sim.loops.axpy)
+}
+
+// axpyLoop은 dst[i] += src[i]·scale 병렬 루프입니다 (drift, kick, 힘장 가속도 합).
+type axpyLoop struct {
+	dst, src []Vector
+	scale    float64
+}
+
+func (l *axpyLoop) run(_, lo, hi int) {
+	for i := lo; i < hi; i++ {
+		l.dst[i] = l.dst[i].Add(l.src[i].Mul(l.scale))
+	}
+}
+
+// forceCache는 스텝 끝에서 계산한 가속도를 다음 스텝 시작에 재사용합니다 (First Same As Last).
+// 재사용 여부는 상태로 판단합니다: 계산할 때의 위치, 시각, 질량, 전하, Gravity, 힘장 목록(AddForceField 횟수와 길이)을
+// 기억해 두고 하나라도 달라지면 다시 계산합니다. 따라서 SolidBoundary 반사, Pos 직접 수정,
+// ApplySpecies나 ReadSnapshot의 질량·전하 변경, AddForceField, Gravity나 Dt 변경 뒤에도 올바른 힘을 씁니다. PeriodicBoundary로 한 주기만큼
+// 옮겨진 파티클은 힘이 변하지 않으므로 그대로 재사용합니다.
+// 두 버퍼를 번갈아 쓰므로 update 뒤에도 get이 돌려준 이전 가속도가 유지됩니다.
+//
+// 힘장 내부 파라미터(예: P3M.G)를 스텝 사이에 바꾼 경우는 알 수 없으므로 Reset()으로 무효화하세요.
+type forceCache struct {
+	acc     []Vector
+	next    []Vector
+	pos     []Vector  // acc를 계산한 위치
+	mass    []float64 // acc를 계산한 질량
+	charge  []float64 // acc를 계산한 전하
+	t       float64   // acc를 계산한 시각
+	gravity Vector
+	fields  [2]int // sim.fieldsVersion, len(sim.ForceFields)
+	valid   bool
+}
+
+// get은 스텝 시작 위치와 시각의 가속도를 반환합니다.
+func (c *forceCache) get(sim *Simulator) []Vector {
+	t := stepStart(sim)
+	if !c.matches(sim, t) {
+		c.acc = accelerationsAt(sim, sim.Pos, t, c.acc)
+	}
+	return c.acc
+}
+
+// update는 현재 위치와 스텝 끝 시각의 가속도를 다른 버퍼에 계산해 다음 스텝용으로 저장하고 반환합니다.
+func (c *forceCache) update(sim *Simulator) []Vector {
+	c.next = accelerationsAt(sim, sim.Pos, sim.T, c.next)
+	c.acc, c.next = c.next, c.acc
+	c.pos = resize(c.pos, sim.N)
+	copy(c.pos, sim.Pos)
+	c.mass = resize(c.mass, len(sim.Mass))
+	copy(c.mass, sim.Mass)
+	c.charge = resize(c.charge, len(sim.Charge))
+	copy(c.charge, sim.Charge)
+	c.t = sim.T
+	c.gravity = sim.Gravity
+	c.fields = [2]int{sim.fieldsVersion, len(sim.ForceFields)}
+	c.valid = true
+	return c.acc
+}
+
+// matches는 캐시된 가속도가 시각 t, 현재 상태의 가속도와 같은지 판단합니다.
+func (c *forceCache) matches(sim *Simulator, t float64) bool {
+	if !c.valid || len(c.pos) != sim.N || c.gravity != sim.Gravity ||
+		c.fields != [2]int{sim.fieldsVersion, len(sim.ForceFields)} ||
+		math.Abs(t-c.t) > 1e-9*math.Abs(sim.Dt) ||
+		!slices.Equal(c.mass, sim.Mass) || !slices.Equal(c.charge, sim.Charge) {
+		return false
+	}
+	for i, p := range sim.Pos {
+		if p != c.pos[i] && !wrapped(c.pos[i], p, sim.wrapLength) {
+			return false
+		}
+	}
+	return true
+}
+
+// wrapped는 a에서 b로의 이동이 축마다 0 또는 주기 L 한 번(PeriodicBoundary)인지 판단합니다.
+func wrapped(a, b Vector, L float64) bool {
+	if L <= 0 {
+		return false
+	}
+	same := func(x, y float64) bool {
+		return x == y || math.Abs(math.Abs(y-x)-L) <= 1e-12*L
+	}
+	return same(a.X, b.X) && same(a.Y, b.Y) && same(a.Z, b.Z)
+}
+
+// Reset은 캐시된 가속도를 무효화합니다.
+func (c *forceCache) Reset() {
+	c.valid = false
+}
+
+// ── Euler ────────────────────────────────────────────────────────────────────
+
+// Euler는 반음해적(semi-implicit, symplectic) Euler 적분기입니다.
+//
+//	v_new = v + a(x)·dt
+//	x_new = x + v_new·dt
+//
+// 1차 정확도, 스텝당 힘 계산 1회. Simulator의 기본 적분기입니다.
+type Euler struct {
+	acc []Vector
+}
+
+func (in *Euler) Step(sim *Simulator) {
+	in.acc = accelerationsAt(sim, sim.Pos, stepStart(sim), in.acc)
+	kick(sim, in.acc, sim.Dt)
+	drift(sim, sim.Dt)
+}
+
+// ── Leapfrog ─────────────────────────────────────────────────────────────────
+
+// LeapfrogKDK는 kick-drift-kick leapfrog 적분기입니다.
+//
+//	v_½   = v + a(x)·dt/2
+//	x_new = x + v_½·dt
+//	v_new = v_½ + a(x_new)·dt/2
+//
+// 2차 정확도, 심플렉틱, 시간 가역적. 끝의 가속도를 다음 스텝에 재사용하므로
+// 스텝당 힘 계산 1회입니다.
+type LeapfrogKDK struct {
+	forceCache
+}
+
+func (in *LeapfrogKDK) Step(sim *Simulator) {
+	dt := sim.Dt
+	kick(sim, in.get(sim), dt/2)
+	drift(sim, dt)
+	kick(sim, in.update(sim), dt/2)
+}
+
+// LeapfrogDKD는 drift-kick-drift leapfrog 적분기입니다.
+//
+//	x_½   = x + v·dt/2
+//	v_new = v + a(x_½)·dt
+//	x_new = x_½ + v_new·dt/2
+//
+// 2차 정확도, 심플렉틱, 시간 가역적. 스텝당 힘 계산 1회.
+type LeapfrogDKD struct {
+	acc []Vector
+}
+
+func (in *LeapfrogDKD) Step(sim *Simulator) {
+	dt := sim.Dt
+	drift(sim, dt/2)
+	in.acc = accelerationsAt(sim, sim.Pos, stepStart(sim)+dt/2, in.acc)
+	kick(sim, in.acc, dt)
+	drift(sim, dt/2)
+}
+
+// ── Velocity Verlet ──────────────────────────────────────────────────────────
+
+// VelocityVerlet는 속도 Verlet 적분기입니다.
+//
+//	x_new = x + v·dt + a·dt²/2
+//	v_new = v + (a + a_new)·dt/2
+//
+// KDK leapfrog와 대수적으로 같으며, 끝의 가속도를 재사용해 스텝당 힘 계산 1회입니다.
+type VelocityVerlet struct {
+	forceCache
+	loop verletLoop
+}
+
+func (in *VelocityVerlet) Step(sim *Simulator) {
+	in.loop = verletLoop{sim: sim, acc: in.get(sim), dt: sim.Dt}
+	sim.Parallel.run(sim.N, &in.loop)
+	in.loop.accNew = in.update(sim)
+	sim.Parallel.run(sim.N, &in.loop)
+}
+
+// verletLoop은 VelocityVerlet의 위치 갱신(accNew == nil)과 속도 갱신 병렬 루프입니다.
+type verletLoop struct {
+	sim         *Simulator
+	acc, accNew []Vector
+	dt          float64
+}
+
+func (l *verletLoop) run(_, lo, hi int) {
+	sim, acc, dt := l.sim, l.acc, l.dt
+	if l.accNew == nil {
+		for i := lo; i < hi; i++ {
+			sim.Pos[i] = sim.Pos[i].Add(sim.Vel[i].Mul(dt)).Add(acc[i].Mul(dt * dt / 2))
+		}
+		return
+	}
+	for i := lo; i < hi; i++ {
+		sim.Vel[i] = sim.Vel[i].Add(acc[i].Add(l.accNew[i]).Mul(dt / 2))
+	}
+}
+
+// ── Runge-Kutta ──────────────────────────────────────────────────────────────
+
+// RK4는 고전적 4차 Runge-Kutta 적분기입니다.
+//
+// 4차 정확도이지만 심플렉틱이 아니므로 장기 적분에서 에너지가 표류합니다.
+// 스텝당 힘 계산 4회.
+type RK4 struct {
+	x0, v0 []Vector
+	stage  []Vector
+	sumX   []Vector
+	sumV   []Vector
+	acc    [4][]Vector // 단계별 가속도 k1v..k4v
+
+	// 단계 사이 병렬 루프(run)의 인자
+	sim *Simulator
+	dt  float64
+	k   int // 방금 계산한 가속도 단계 (0..3)
+}
+
+func (in *RK4) Step(sim *Simulator) {
+	n, dt, t0 := sim.N, sim.Dt, stepStart(sim)
+	in.x0 = resize(in.x0, n)
+	in.v0 = resize(in.v0, n)
+	in.stage = resize(in.stage, n)
+	in.sumX = resize(in.sumX, n)
+	in.sumV = resize(in.sumV, n)
+	copy(in.x0, sim.Pos)
+	copy(in.v0, sim.Vel)
+	in.sim, in.dt = sim, dt
+
+	// k1
+	in.acc[0] = accelerationsAt(sim, in.x0, t0, in.acc[0])
+	in.k = 0
+	sim.Parallel.run(n, in)
+	// k2: x + dt/2·k1x, v + dt/2·k1v
+	in.acc[1] = accelerationsAt(sim, in.stage, t0+dt/2, in.acc[1])
+	in.k = 1
+	sim.Parallel.run(n, in)
+	// k3: x + dt/2·k2x, v + dt/2·k2v
+	in.acc[2] = accelerationsAt(sim, in.stage, t0+dt/2, in.acc[2])
+	in.k = 2
+	sim.Parallel.run(n, in)
+	// k4: x + dt·k3x, v + dt·k3v
+	in.acc[3] = accelerationsAt(sim, in.stage, t0+dt, in.acc[3])
+	in.k = 3
+	sim.Parallel.run(n, in)
+}
+
+// run은 가속도 단계 in.k를 합에 더하고 다음 단계 위치(마지막 단계면 sim.Pos, sim.Vel)를 씁니다.
+func (in *RK4) run(_, lo, hi int) {
+	sim, dt := in.sim, in.dt
+	a1, a2, a3, a4 := in.acc[0], in.acc[1], in.acc[2], in.acc[3]
+	switch in.k {
+	case 0:
+		for i := lo; i < hi; i++ {
+			in.sumX[i] = in.v0[i]
+			in.sumV[i] = a1[i]
+			in.stage[i] = in.x0[i].Add(in.v0[i].Mul(dt / 2))
+		}
+	case 1:
+		for i := lo; i < hi; i++ {
+			v2 := in.v0[i].Add(a1[i].Mul(dt / 2))
+			in.sumX[i] = in.sumX[i].Add(v2.Mul(2))
+			in.sumV[i] = in.sumV[i].Add(a2[i].Mul(2))
+			in.stage[i] = in.x0[i].Add(v2.Mul(dt / 2))
+		}
+	case 2:
+		for i := lo; i < hi; i++ {
+			v3 := in.v0[i].Add(a2[i].Mul(dt / 2))
+			in.sumX[i] = in.sumX[i].Add(v3.Mul(2))
+			in.sumV[i] = in.sumV[i].Add(a3[i].Mul(2))
+			in.stage[i] = in.x0[i].Add(v3.Mul(dt))
+		}
+	case 3:
+		for i := lo; i < hi; i++ {
+			v4 := in.v0[i].Add(a3[i].Mul(dt))
+			in.sumX[i] = in.sumX[i].Add(v4)
+			in.sumV[i] = in.sumV[i].Add(a4[i])
+			sim.Pos[i] = in.x0[i].Add(in.sumX[i].Mul(dt / 6))
+			sim.Vel[i] = in.v0[i].Add(in.sumV[i].Mul(dt / 6))
+		}
+	}
+}
+
+// ── Yoshida ──────────────────────────────────────────────────────────────────
+
+// Yoshida 4차 계수: w1 = 1/(2 - 2^(1/3)),  w0 = -2^(1/3)·w1
+var (
+	yoshidaW1 = 1 / (2 - math.Cbrt(2))
+	yoshidaW0 = -math.Cbrt(2) * yoshidaW1
+	yoshidaC  = [4]float64{yoshidaW1 / 2, (yoshidaW0 + yoshidaW1) / 2, (yoshidaW0 + yoshidaW1) / 2, yoshidaW1 / 2}
+	yoshidaD  = [3]float64{yoshidaW1, yoshidaW0, yoshidaW1}
+)
+
+// Yoshida4는 Yoshida(1990)의 4차 심플렉틱 적분기입니다.
+// leapfrog 3개를 계수 (w1, w0, w1)로 합성합니다:
+//
+//	drift c1 → kick d1 → drift c2 → kick d2 → drift c3 → kick d3 → drift c4
+//
+// 4차 정확도, 심플렉틱, 시간 가역적. 스텝당 힘 계산 3회.
+type Yoshida4 struct {
+	acc []Vector
+}
+
+func (in *Yoshida4) Step(sim *Simulator) {
+	dt, t := sim.Dt, stepStart(sim)
+	for s := 0; s < 3; s++ {
+		drift(sim, yoshidaC[s]*dt)
+		t += yoshidaC[s] * dt
+		in.acc = accelerationsAt(sim, sim.Pos, t, in.acc)
+		kick(sim, in.acc, yoshidaD[s]*dt)
+	}
+	drift(sim, yoshidaC[3]*dt)
+}
@@ -0,0 +1,252 @@
+package atom3D
+
+import (
+	"math"
+	"testing"
+)
+
+// springField는 a = -x 인 조화 진동자 힘장입니다 (ω = 1).
+type springField struct{}
+
+func (springField) Accelerations(sim *Simulator) []Vector {
+	acc := make([]Vector, sim.N)
+	for i := range acc {
+		acc[i] = sim.Pos[i].Mul(-1)
+	}
+	return acc
+}
+
+// oscillatorError는 t=1까지 적분한 뒤 해석해 x=cos t, v=-sin t 와의 오차를 반환합니다.
+func oscillatorError(in Integrator, steps int) float64 {
+	sim := NewSimulator(1/float64(steps), []int{0}, []Vector{{1, 0, 0}}, []Vector{{0, 0, 0}}, Vector{})
+	sim.Integrator = in
+	sim.AddForceField(springField{})
+	for i := 0; i < steps; i++ {
+		sim.Step()
+	}
+	return sim.Pos[0].Sub(Vector{math.Cos(1), 0, 0}).Abs() + sim.Vel[0].Sub(Vector{-math.Sin(1), 0, 0}).Abs()
+}
+
+func TestIntegratorOrder(t *testing.T) {
+	cases := []struct {
+		name  string
+		new   func() Integrator
+		order float64
+	}{
+		{"Euler", func() Integrator { return &Euler{} }, 1},
+		{"LeapfrogKDK", func() Integrator { return &LeapfrogKDK{} }, 2},
+		{"LeapfrogDKD", func() Integrator { return &LeapfrogDKD{} }, 2},
+		{"VelocityVerlet", func() Integrator { return &VelocityVerlet{} }, 2},
+		{"RK4", func() Integrator { return &RK4{} }, 4},
+		{"Yoshida4", func() Integrator { return &Yoshida4{} }, 4},
+	}
+	for _, c := range cases {
+		e1 := oscillatorError(c.new(), 50)
+		e2 := oscillatorError(c.new(), 100)
+		order := math.Log2(e1 / e2)
+		if math.Abs(order-c.order) > 0.3 {
+			t.Errorf("%s: observed order %.2f (errors %.3e, %.3e), want %.0f", c.name, order, e1, e2, c.order)
+		}
+	}
+}
+
+func TestIntegratorReversibility(t *testing.T) {
+	for _, in := range []Integrator{&LeapfrogKDK{}, &LeapfrogDKD{}, &Yoshida4{}} {
+		sim := NewSimulator(0.1, []int{0}, []Vector{{1, 0, 0}}, []Vector{{0, 0.5, 0}}, Vector{})
+		sim.Integrator = in
+		sim.AddForceField(springField{})
+		for i := 0; i < 100; i++ {
+			sim.Step()
+		}
+		sim.Dt = -sim.Dt
+		for i := 0; i < 100; i++ {
+			sim.Step()
+		}
+		if d := sim.Pos[0].Sub(Vector{1, 0, 0}).Abs(); d > 1e-10 {
+			t.Errorf("%T: position after forward/backward run differs by %.3e", in, d)
+		}
+	}
+}
+
+// timeField는 a = (t, 0, 0) 인 시간 의존 힘장입니다.
+type timeField struct{}
+
+func (timeField) Accelerations(sim *Simulator) []Vector {
+	acc := make([]Vector, sim.N)
+	for i := range acc {
+		acc[i] = Vector{sim.T, 0, 0}
+	}
+	return acc
+}
+
+// fieldField는 a = (q/m, 0, 0) 인 균일 전기장 힘장입니다 (질량·전하 의존).
+type fieldField struct{}
+
+func (fieldField) Accelerations(sim *Simulator) []Vector {
+	acc := make([]Vector, sim.N)
+	for i := range acc {
+		acc[i] = Vector{sim.Charge[i] / sim.Mass[i], 0, 0}
+	}
+	return acc
+}
+
+// countingField는 계산 횟수만 세는 힘이 없는 힘장입니다.
+type countingField struct{ calls *int }
+
+func (f countingField) Accelerations(sim *Simulator) []Vector {
+	*f.calls++
+	return make([]Vector, sim.N)
+}
+
+// 각 단계의 가속도는 그 단계의 시각에서 계산되어야 합니다.
+// a = t 이면 v(T) = T²/2 이며, 사다리꼴·중점·Simpson 규칙에 해당하는 적분기는 이를 정확히 냅니다.
+func TestIntegratorTimeDependence(t *testing.T) {
+	dt, steps := 0.1, 10
+	T := dt * float64(steps)
+	for _, c := range []struct {
+		in   Integrator
+		want float64
+	}{
+		{&Euler{}, dt * dt * float64(steps*(steps-1)) / 2}, // 왼쪽 직사각형 합
+		{&LeapfrogKDK{}, T * T / 2},
+		{&LeapfrogDKD{}, T * T / 2},
+		{&VelocityVerlet{}, T * T / 2},
+		{&RK4{}, T * T / 2},
+		{&Yoshida4{}, T * T / 2},
+	} {
+		sim := NewSimulator(dt, []int{0}, []Vector{{}}, []Vector{{}}, Vector{})
+		sim.Integrator = c.in
+		sim.AddForceField(timeField{})
+		for i := 0; i < steps; i++ {
+			sim.Step()
+		}
+		if got := sim.Vel[0].X; math.Abs(got-c.want) > 1e-12 {
+			t.Errorf("%T: v(%.1f) = %.15f, want %.15f", c.in, T, got, c.want)
+		}
+	}
+}
+
+// 캐시된 끝 가속도는 스텝 사이에 상태가 바뀌면 버려야 하며, 주기 이동만 있으면 재사용해야 합니다.
+func TestForceCacheInvalidation(t *testing.T) {
+	newSim := func(in Integrator) *Simulator {
+		sim := NewSimulator(0.05, []int{0, 0}, []Vector{{1, 0, 0}, {0, 0.5, 0}}, []Vector{{0, 1, 0}, {0.3, 0, 0}}, Vector{})
+		sim.Integrator = in
+		sim.AddForceField(springField{})
+		sim.AddForceField(timeField{})
+		sim.AddForceField(fieldField{})
+		sim.Charge[0], sim.Charge[1] = 1, -0.5
+		return sim
+	}
+	changes := map[string]func(sim *Simulator){
+		"Pos":           func(sim *Simulator) { sim.Pos[0].X = -sim.Pos[0].X },
+		"SolidBoundary": func(sim *Simulator) { sim.Pos[1].X = 0.61; sim.Vel[1].X = 40; sim.SolidBoundary(1.2) },
+		"AddForceField": func(sim *Simulator) { sim.AddForceField(UniformGravity{Vector{0, 0, -1}}) },
+		"Gravity":       func(sim *Simulator) { sim.Gravity = Vector{0, -2, 0} },
+		"Dt":            func(sim *Simulator) { sim.Dt = 0.02 },
+		"Mass":          func(sim *Simulator) { sim.Mass[0] = 3 },
+		"Charge":        func(sim *Simulator) { sim.Charge[1] = 2 },
+	}
+	for name, change := range changes {
+		for _, newIn := range []func() Integrator{
+			func() Integrator { return &LeapfrogKDK{} },
+			func() Integrator { return &VelocityVerlet{} },
+		} {
+			// 캐시가 채워진 적분기와, 같은 상태에서 새로 시작한 적분기가 같은 결과를 내야 합니다.
+			warm := newSim(newIn())
+			for i := 0; i < 3; i++ {
+				warm.Step()
+			}
+			change(warm)
+			cold := newSim(newIn())
+			cold.Count, cold.T, cold.Dt, cold.Gravity = warm.Count, warm.T, warm.Dt, warm.Gravity
+			cold.ForceFields = append([]ForceField(nil), warm.ForceFields...)
+			copy(cold.Pos, warm.Pos)
+			copy(cold.Vel, warm.Vel)
+			copy(cold.Mass, warm.Mass)
+			copy(cold.Charge, warm.Charge)
+			warm.Step()
+			cold.Step()
+			for i := range warm.Pos {
+				if warm.Pos[i] != cold.Pos[i] || warm.Vel[i] != cold.Vel[i] {
+					t.Errorf("%s, %T: cached step gives x = %v, v = %v; fresh step gives x = %v, v = %v",
+						name, warm.Integrator, warm.Pos[i], warm.Vel[i], cold.Pos[i], cold.Vel[i])
+				}
+			}
+		}
+	}
+
+	// 주기 경계로 한 주기 옮겨진 파티클만 있으면 스텝당 힘 계산은 1회입니다.
+	calls := 0
+	sim := NewSimulator(0.05, []int{0}, []Vector{{0.99, 0, 0}}, []Vector{{1, 0, 0}}, Vector{})
+	sim.Integrator = &LeapfrogKDK{}
+	sim.AddForceField(countingField{&calls})
+	sim.Step()
+	sim.PeriodicBoundary(2)
+	calls = 0
+	sim.Step()
+	if calls != 1 {
+		t.Errorf("step after a periodic wrap computed forces %d times, want 1", calls)
+	}
+}
+
+// p3mStepSimulator는 P3M 중력으로 움직이는 n³ 파티클 시뮬레이터입니다 (할당 측정용).
+func p3mStepSimulator(n int, in Integrator, par Parallel) *Simulator {
+	L := 10.
+	sim := ewaldSimulator(perturbedLattice(n, L, 0.5, 3), L)
+	sim.Dt = 1e-3
+	p3m := NewP3M(32, L, 1.)
+	sim.GridSize = p3m.RCut
+	sim.AddForceField(p3m)
+	sim.Integrator = in
+	sim.Parallel = par
+	return sim
+}
+
+// 정상 상태 스텝은 (워커 하나에서) 아무것도 할당하지 않아야 합니다.
+// 병렬 루프도 클로저 대신 솔버가 가진 루프 몸체를 넘기므로 (Parallel.run) N과 무관하게 0입니다.
+func TestStepAllocations(t *testing.T) {
+	for _, in := range []func() Integrator{
+		func() Integrator { return &Euler{} },
+		func() Integrator { return &LeapfrogKDK{} },
+		func() Integrator { return &LeapfrogDKD{} },
+		func() Integrator { return &VelocityVerlet{} },
+		func() Integrator { return &RK4{} },
+		func() Integrator { return &Yoshida4{} },
+	} {
+		for _, n := range []int{8, 16} {
+			sim := p3mStepSimulator(n, in(), Parallel{Workers: 1})
+			sim.Step()
+			if allocs := testing.AllocsPerRun(5, sim.Step); allocs != 0 {
+				t.Errorf("%T: %.0f allocs/step (N = %d), want 0", sim.Integrator, allocs, n*n*n)
+			}
+		}
+	}
+
+	// 힘장 없이 Gravity만 있는 스텝과 경계 처리도 마찬가지입니다.
+	sim := NewSimulator(1e-3, make([]int, 64), perturbedLattice(4, 4, 0.5, 1), make([]Vector, 64), Vector{0, 0, -1})
+	sim.Parallel = Parallel{Workers: 1}
+	if allocs := testing.AllocsPerRun(5, func() {
+		sim.Step()
+		sim.PeriodicBoundary(4)
+		sim.SolidBoundary(4)
+	}); allocs != 0 {
+		t.Errorf("gravity-only step with boundaries: %.0f allocs, want 0", allocs)
+	}
+}
+
+func BenchmarkStepP3M(b *testing.B) {
+	for _, c := range []struct {
+		name string
+		par  Parallel
+	}{{"serial", Parallel{Workers: 1}}, {"parallel", Parallel{}}} {
+		b.Run(c.name, func(b *testing.B) {
+			sim := p3mStepSimulator(16, &LeapfrogKDK{}, c.par)
+			sim.Step()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				sim.Step()
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package atom3D
+
+import "math"
+
+// ── Verlet 이웃 목록 ─────────────────────────────────────────────────────────
+
+// NeighborList는 파티클마다 Cutoff + Skin 안의 이웃을 저장해 두는 Verlet 이웃 목록입니다.
+// 마지막 구성 이후 어떤 파티클도 Skin/2 넘게 움직이지 않았다면 Cutoff 안의 쌍은 모두 목록에
+// 있으므로, 힘 계산마다 셀 27개를 다시 훑는 대신 목록만 읽습니다. Update가 최대 변위를 추적해
+// 필요할 때만 셀 목록(MakeGrid/ForEachNeighbor)으로 다시 구성합니다.
+//
+// P3M.Neighbors, PairForce.Neighbors에 지정하면 PP 보정과 쌍 퍼텐셜이 이 목록을 사용합니다.
+//
+//	nl := atom3D.NewNeighborList(p3m.RCut, 0.3*p3m.RCut, true)
+//	p3m.Neighbors = nl
+//	sim.GridSize = nl.Cutoff + nl.Skin
+//
+// 사전 조건: sim.RegionSize 설정, sim.GridSize >= Cutoff + Skin (이웃 누락 방지)
+type NeighborList struct {
+	Cutoff   float64 // 상호작용 컷오프 반경
+	Skin     float64 // 여유 두께 (클수록 재구성이 드물고 목록이 깁니다)
+	Periodic bool    // 주기 경계 최소 이미지 사용 (거리와 변위 모두)
+
+	// 파티클 i의 이웃은 Index[Start[i]:Start[i+1]] 입니다 (ForEachNeighbor 순서, 양방향 저장).
+	Start []int // 파티클별 시작 위치, 길이 N + 1
+	Index []int // 이웃 파티클 번호
+
+	Builds int // 지금까지 구성한 횟수
+
+	reference []Vector     // 마지막 구성 때의 위치
+	order     []int        // 마지막 구성 때의 셀 순서 (ParticleIndex 복사본)
+	maxMoves  []float64    // 워커별 최대 변위²
+	key       neighborKey  // 마지막 구성의 파라미터
+	loop      neighborLoop // 병렬 루프 몸체 (Parallel.run)
+}
+
+// neighborKey는 목록을 다시 구성해야 하는 파라미터 묶음입니다.
+type neighborKey struct {
+	N                  int
+	cutoff, skin, size float64
+	periodic           bool
+}
+
+// NewNeighborList는 컷오프, 여유 두께, 경계 조건으로 NeighborList를 생성합니다.
+// 목록은 처음 Update할 때 구성됩니다.
+func NewNeighborList(cutoff, skin float64, periodic bool) *NeighborList {
+	return &NeighborList{Cutoff: cutoff, Skin: skin, Periodic: periodic}
+}
+
+// Of는 파티클 i의 이웃 번호를 반환합니다 (Index의 부분 슬라이스).
+func (nl *NeighborList) Of(i int) []int {
+	return nl.Index[nl.Start[i]:nl.Start[i+1]]
+}
+
+// Order는 마지막 구성 때의 셀 순서입니다. 이 순서로 돌면 이웃 위치가 캐시에 남아 있습니다.
+func (nl *NeighborList) Order() []int {
+	return nl.order
+}
+
+// Update는 필요하면 목록을 다시 구성하고, 구성했으면 true를 반환합니다.
+// 처음 호출, N이나 Cutoff/Skin/Periodic/sim.RegionSize가 바뀐 경우,
+// 마지막 구성 이후 최대 변위가 Skin/2를 넘은 경우에 다시 구성합니다.
+func (nl *NeighborList) Update(sim *Simulator) bool {
+	key := neighborKey{sim.N, nl.Cutoff, nl.Skin, sim.RegionSize, nl.Periodic}
+	if nl.Builds == 0 || key != nl.key || nl.MaxDisplacement(sim) > nl.Skin/2 {
+		nl.Build(sim)
+		return true
+	}
+	return false
+}
+
+// MaxDisplacement는 마지막 구성 이후 파티클의 최대 변위를 반환합니다.
+// 주기 경계에서는 최소 이미지 규약을 따르므로 PeriodicBoundary로 반대편에 넘어간 파티클도 실제 이동 거리로 잽니다.
+func (nl *NeighborList) MaxDisplacement(sim *Simulator) float64 {
+	if len(nl.reference) != sim.N {
+		return math.Inf(1)
+	}
+	nl.maxMoves = resize(nl.maxMoves, sim.Parallel.workers())
+	clear(nl.maxMoves)
+	nl.loop = neighborLoop{nl: nl, sim: sim, phase: neighborMoves}
+	sim.Parallel.run(sim.N, &nl.loop)
+
+	largest := 0.0
+	for _, m := range nl.maxMoves {
+		largest = max(largest, m)
+	}
+	return math.Sqrt(largest)
+}
+
+// Build는 셀 목록에서 r < Cutoff + Skin 인 쌍을 모아 목록을 구성합니다 (sim.MakeGrid 포함).
+// 파티클별 개수 세기, 누적합, 채우기의 두 번 순회라 결과가 워커 수와 조각 크기에 무관하며,
+// 배열은 재사용하므로 N과 총 쌍 수가 늘지 않으면 새로 할당하지 않습니다.
+func (nl *NeighborList) Build(sim *Simulator) {
+	N := sim.N
+	sim.MakeGrid()
+	nl.Start = resize(nl.Start, N+1)
+	start := nl.Start
+	nl.loop = neighborLoop{nl: nl, sim: sim, reach: nl.Cutoff + nl.Skin, phase: neighborCount}
+
+	// 1단계: 파티클별 이웃 수 (start[i+1]에 임시 저장)
+	start[0] = 0
+	sim.Parallel.run(N, &nl.loop)
+	for i := 0; i < N; i++ {
+		start[i+1] += start[i]
+	}
+
+	// 2단계: 채우기
+	nl.Index = resize(nl.Index, start[N])
+	nl.loop.phase = neighborFill
+	sim.Parallel.run(N, &nl.loop)
+
+	nl.reference = resize(nl.reference, N)
+	copy(nl.reference, sim.Pos)
+	nl.order = resize(nl.order, N)
+	copy(nl.order, sim.ParticleIndex)
+	nl.key = neighborKey{N, nl.Cutoff, nl.Skin, sim.RegionSize, nl.Periodic}
+	nl.Builds++
+}
+
+// neighborLoop은 MaxDisplacement와 Build의 파티클별 병렬 루프입니다.
+type neighborLoop struct {
+	nl    *NeighborList
+	sim   *Simulator
+	reach float64 // Cutoff + Skin
+	phase int
+}
+
+const (
+	neighborMoves = iota // 워커별 최대 변위² (MaxDisplacement)
+	neighborCount        // 파티클별 이웃 수 (Build 1단계)
+	neighborFill         // Index 채우기 (Build 2단계)
+)
+
+func (l *neighborLoop) run(w, lo, hi int) {
+	nl, sim := l.nl, l.sim
+	switch l.phase {
+	case neighborMoves:
+		moves := nl.maxMoves
+		for i := lo; i < hi; i++ {
+			d := sim.Pos[i].Sub(nl.reference[i])
+			if nl.Periodic {
+				d = minimumImage(d, sim.RegionSize)
+			}
+			moves[w] = max(moves[w], d.Dot(d))
+		}
+	case neighborCount:
+		for _, i := range sim.ParticleIndex[lo:hi] {
+			count := 0
+			nl.visit(sim, i, l.reach, func(j int) { count++ })
+			nl.Start[i+1] = count
+		}
+	case neighborFill:
+		index := nl.Index
+		for _, i := range sim.ParticleIndex[lo:hi] {
+			k := nl.Start[i]
+			nl.visit(sim, i, l.reach, func(j int) {
+				index[k] = j
+				k++
+			})
+		}
+	}
+}
+
+// visit은 셀 목록에서 파티클 i와 거리 reach 미만인 이웃마다 visit(j)를 호출합니다 (자기 자신 제외).
+func (nl *NeighborList) visit(sim *Simulator, i int, reach float64, visit func(j int)) {
+	reach2 := reach * reach
+	sim.ForEachNeighbor(i, nl.Periodic, func(j int) {
+		if j == i {
+			return
+		}
+		var d Vector
+		if nl.Periodic {
+			d = sim.PeriodicDisplacement(i, j)
+		} else {
+			d = sim.Pos[j].Sub(sim.Pos[i])
+		}
+		if d.Dot(d) < reach2 {
+			visit(j)
+		}
+	})
+}
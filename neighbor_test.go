@@ -0,0 +1,200 @@
+package atom3D
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// Verlet 목록은 r < Cutoff + Skin 인 모든 쌍을 (양방향으로) 담아야 합니다.
+func TestNeighborList(t *testing.T) {
+	L := 10.
+	pos := randomPositions(600, L, 41)
+	for _, periodic := range []bool{false, true} {
+		sim := ewaldSimulator(pos, L)
+		nl := NewNeighborList(1.2, 0.3, periodic)
+		sim.GridSize = nl.Cutoff + nl.Skin
+		if !nl.Update(sim) || nl.Builds != 1 {
+			t.Fatalf("periodic %v: first Update did not build the list", periodic)
+		}
+		if len(nl.Start) != sim.N+1 || nl.Start[sim.N] != len(nl.Index) {
+			t.Fatalf("periodic %v: Start has %d entries ending at %d, Index has %d", periodic, len(nl.Start), nl.Start[sim.N], len(nl.Index))
+		}
+		if order := slices.Sorted(slices.Values(nl.Order())); len(order) != sim.N || order[0] != 0 || order[sim.N-1] != sim.N-1 || slices.Compact(order)[sim.N-1] != sim.N-1 {
+			t.Fatalf("periodic %v: Order is not a permutation of %d particles", periodic, sim.N)
+		}
+
+		reach := nl.Cutoff + nl.Skin
+		for i := 0; i < sim.N; i++ {
+			want := []int{}
+			for j := 0; j < sim.N; j++ {
+				d := sim.Pos[j].Sub(sim.Pos[i])
+				if periodic {
+					d = sim.PeriodicDisplacement(i, j)
+				}
+				if j != i && d.Abs() < reach {
+					want = append(want, j)
+				}
+			}
+			got := slices.Sorted(slices.Values(nl.Of(i)))
+			if !slices.Equal(got, want) {
+				t.Fatalf("periodic %v: neighbors of %d = %v, want %v", periodic, i, got, want)
+			}
+		}
+	}
+}
+
+// Update는 최대 변위가 Skin/2를 넘거나 파라미터가 바뀔 때만 다시 구성해야 합니다.
+func TestNeighborListUpdate(t *testing.T) {
+	L := 10.
+	sim := ewaldSimulator(randomPositions(400, L, 43), L)
+	nl := NewNeighborList(1.2, 0.4, true)
+	sim.GridSize = nl.Cutoff + nl.Skin
+	nl.Update(sim)
+
+	// 모든 파티클을 Skin/2보다 조금 덜 움직이면 그대로입니다.
+	rng := rand.New(rand.NewSource(1))
+	for i := range sim.Pos {
+		dir := Vector{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()}
+		sim.Pos[i] = sim.Pos[i].Add(dir.Mul(0.19 / dir.Abs()))
+	}
+	sim.PeriodicBoundary(L)
+	if nl.Update(sim) {
+		t.Errorf("rebuilt after max displacement %.3f <= Skin/2", nl.MaxDisplacement(sim))
+	}
+
+	// 주기 경계를 넘어 반대편으로 옮겨진 파티클은 실제 이동 거리로 잽니다.
+	sim.Pos[0] = Vector{L/2 - 0.05, 0, 0}
+	nl.Build(sim)
+	sim.Pos[0] = Vector{-L/2 + 0.05, 0, 0}
+	if d := nl.MaxDisplacement(sim); math.Abs(d-0.1) > 1e-12 {
+		t.Errorf("displacement across the boundary = %v, want 0.1", d)
+	}
+	if nl.Update(sim) {
+		t.Error("rebuilt after a 0.1 move across the periodic boundary")
+	}
+
+	builds := nl.Builds
+	sim.Pos[1] = sim.Pos[1].Add(Vector{0.21, 0, 0})
+	if !nl.Update(sim) || nl.Builds != builds+1 {
+		t.Error("did not rebuild after a move > Skin/2")
+	}
+	nl.Skin = 0.5
+	if !nl.Update(sim) {
+		t.Error("did not rebuild after Skin changed")
+	}
+
+	// 재구성하지 않는 Update와 같은 N의 재구성은 (워커 하나에서) 아무것도 할당하지 않습니다.
+	sim.Parallel = Parallel{Workers: 1}
+	if allocs := testing.AllocsPerRun(10, func() { nl.Update(sim) }); allocs != 0 {
+		t.Errorf("Update without rebuild: %.0f allocs, want 0", allocs)
+	}
+	if allocs := testing.AllocsPerRun(10, func() { nl.Build(sim) }); allocs != 0 {
+		t.Errorf("Build: %.0f allocs, want 0", allocs)
+	}
+}
+
+// Verlet 목록을 쓰는 P3M은 매 스텝 셀 목록으로 계산한 힘과 같아야 하며,
+// 목록은 몇 스텝마다 한 번만 다시 구성되어야 합니다.
+func TestVerletP3M(t *testing.T) {
+	for _, isolated := range []bool{false, true} {
+		L := 10.
+		sim := ewaldSimulator(perturbedLattice(8, L, 0.5, 5), L)
+		sim.Dt = 1e-2
+		rng := rand.New(rand.NewSource(2))
+		for i := range sim.Vel {
+			sim.Vel[i] = Vector{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()}.Mul(0.5)
+		}
+		p3m, ref := NewP3M(16, L, 1.), NewP3M(16, L, 1.)
+		if isolated {
+			p3m, ref = NewIsolatedP3M(16, L, 1.), NewIsolatedP3M(16, L, 1.)
+		}
+		p3m.Neighbors = NewNeighborList(p3m.RCut, 0.3*p3m.RCut, !isolated)
+		sim.GridSize = p3m.Neighbors.Cutoff + p3m.Neighbors.Skin
+		sim.AddForceField(p3m)
+
+		steps := 30
+		for s := 0; s < steps; s++ {
+			sim.Step()
+			if !isolated {
+				sim.PeriodicBoundary(L)
+			}
+			got, phi := p3m.Compute(sim)
+			want, wantPhi := ref.Compute(sim)
+			for i := range want {
+				if got[i].Sub(want[i]).Abs() > 1e-10*(1+want[i].Abs()) || math.Abs(phi[i]-wantPhi[i]) > 1e-10*(1+math.Abs(wantPhi[i])) {
+					t.Fatalf("isolated %v, step %d: particle %d: acc %v, Φ %v; cell list acc %v, Φ %v",
+						isolated, s, i, got[i], phi[i], want[i], wantPhi[i])
+				}
+			}
+		}
+		if b := p3m.Neighbors.Builds; b < 2 || b > steps/4 {
+			t.Errorf("isolated %v: %d builds in %d steps, want between 2 and %d", isolated, b, steps, steps/4)
+		}
+	}
+}
+
+// Verlet 목록을 쓰는 PairForce는 셀 목록과 같은 가속도, 에너지, 비리얼을 내야 합니다.
+func TestVerletPairForce(t *testing.T) {
+	L := 8.
+	sim := ewaldSimulator(perturbedLattice(6, L, 0.5, 7), L)
+	for i := range sim.Id {
+		sim.Id[i] = i % 2
+	}
+	pf, ref := NewPairForce(LennardJones{Epsilon: 1, Sigma: 1}, 2.5), NewPairForce(LennardJones{Epsilon: 1, Sigma: 1}, 2.5)
+	for _, f := range []*PairForce{pf, ref} {
+		f.SetPair(0, 1, Morse{D: 2, A: 1.5, R0: 1.1})
+		f.Shift = ForceShift
+		f.Periodic = true
+	}
+	pf.Neighbors = NewNeighborList(2.5, 0.4, true)
+	sim.GridSize = 2.9
+
+	rng := rand.New(rand.NewSource(3))
+	for s := 0; s < 20; s++ {
+		for i := range sim.Pos {
+			sim.Pos[i] = sim.Pos[i].Add(Vector{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()}.Mul(0.02))
+		}
+		sim.PeriodicBoundary(L)
+		acc, energy, virial := pf.Compute(sim)
+		wantAcc, wantEnergy, wantVirial := ref.Compute(sim)
+		for i := range wantAcc {
+			if acc[i].Sub(wantAcc[i]).Abs() > 1e-10*(1+wantAcc[i].Abs()) || math.Abs(energy[i]-wantEnergy[i]) > 1e-10*(1+math.Abs(wantEnergy[i])) {
+				t.Fatalf("step %d: particle %d: acc %v, E %v; cell list acc %v, E %v", s, i, acc[i], energy[i], wantAcc[i], wantEnergy[i])
+			}
+		}
+		if math.Abs(virial-wantVirial) > 1e-10*(1+math.Abs(wantVirial)) {
+			t.Fatalf("step %d: virial %v, cell list %v", s, virial, wantVirial)
+		}
+	}
+	if b := pf.Neighbors.Builds; b < 2 || b > 10 {
+		t.Errorf("%d builds in 20 steps, want between 2 and 10", b)
+	}
+}
+
+// 정지한 N = 32768 격자에서 PP 보정 비용: 셀 목록 순회 대 Verlet 목록 (재구성 없음).
+func BenchmarkPPCorrections(b *testing.B) {
+	L := 32.
+	for _, verlet := range []bool{false, true} {
+		name := "CellList"
+		if verlet {
+			name = "Verlet"
+		}
+		b.Run(name, func(b *testing.B) {
+			sim := ewaldSimulator(perturbedLattice(32, L, 0.5, 9), L)
+			p3m := NewP3M(32, L, 1.)
+			sim.GridSize = 1.3 * p3m.RCut
+			if verlet {
+				p3m.Neighbors = NewNeighborList(p3m.RCut, 0.3*p3m.RCut, true)
+			}
+			sim.MakeGrid()
+			p3m.shortRange(sim)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p3m.shortRange(sim)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package atom3D
+
+import "math"
+
+// ── BarnesHut ────────────────────────────────────────────────────────────────
+
+// BarnesHut는 팔분트리(octree)로 중력을 O(N log N)에 근사하는 ForceField입니다.
+// 비주기(고립) 경계 전용이며, 격자 해상도가 고정된 P3M과 달리 밀집된 영역일수록
+// 트리가 깊어져 해상도가 자동으로 따라갑니다.
+//
+// 파티클 i에서 노드까지 거리 d (노드 질량중심 기준), 노드 한 변 s에 대해 s/d < Theta 이면
+// 노드를 다중극 전개로 근사하고, 아니면 자식으로 내려갑니다. 잎 노드는 직접합합니다.
+//
+//	단극:   Φ = -G·M/√(r²+ε²)                 a = -G·M·r/(r²+ε²)^{3/2}
+//	사중극: Φ = -G/2 · rᵀQr/r⁵                 a = G·[Qr/r⁵ - 5/2·(rᵀQr)·r/r⁷]
+//
+// r = x_i - x_com, Q = Σ m(3dd - d²I) (질량중심 기준 무대각합 사중극)이며,
+// 연화(Plummer ε)는 단극과 직접합에만 적용합니다 (사중극은 먼 노드에서만 쓰이므로).
+//
+// 참고: Barnes & Hut, Nature 324, 446 (1986).
+type BarnesHut struct {
+	G          float64 // 중력 상수
+	Theta      float64 // 열림각 (0이면 직접합과 같음, 보통 0.3–0.7)
+	Softening  float64 // Plummer 연화 길이 ε
+	LeafSize   int     // 잎 노드의 최대 파티클 수
+	Quadrupole bool    // true: 단극에 사중극 모멘트를 더함
+
+	Potential []float64 // 마지막 Accelerations 호출의 파티클별 퍼텐셜 (단위 질량당)
+}
+
+// NewBarnesHut은 사중극 모멘트를 포함하고 잎 크기 8인 Barnes–Hut 솔버를 생성합니다.
+func NewBarnesHut(G, theta, softening float64) *BarnesHut {
+	return &BarnesHut{
+		G:          G,
+		Theta:      theta,
+		Softening:  softening,
+		LeafSize:   8,
+		Quadrupole: true,
+	}
+}
+
+// Compute는 가속도와 파티클별 퍼텐셜(단위 질량당, 자기 자신 제외)을 계산합니다.
+// 트리는 호출마다 새로 만들며, 트리 탐색은 파티클별로 병렬 처리합니다.
+func (bh *BarnesHut) Compute(sim *Simulator) ([]Vector, []float64) {
+	N := sim.N
+	acc := make([]Vector, N)
+	pot := make([]float64, N)
+	if N == 0 {
+		return acc, pot
+	}
+	tree := buildOctree(sim.Pos, sim.Mass, bh.LeafSize)
+
+	stacks := make([][]int, sim.Parallel.workers())
+	sim.Parallel.For(N, func(w, lo, hi int) {
+		for i := lo; i < hi; i++ {
+			acc[i], pot[i] = bh.walk(tree, sim.Pos, sim.Mass, i, &stacks[w])
+		}
+	})
+
+	return acc, pot
+}
+
+// walk는 파티클 i에 대해 트리를 탐색해 가속도와 퍼텐셜을 반환합니다.
+// stack은 워커가 재사용하는 작업 스택입니다.
+func (bh *BarnesHut) walk(tree *octree, pos []Vector, mass []float64, i int, stack *[]int) (Vector, float64) {
+	x := pos[i]
+	eps2 := bh.Softening * bh.Softening
+	var a Vector
+	phi := 0.0
+
+	s := append((*stack)[:0], 0)
+	defer func() { *stack = s[:0] }()
+	for len(s) > 0 {
+		node := &tree.nodes[s[len(s)-1]]
+		s = s[:len(s)-1]
+
+		if node.leaf {
+			for _, j := range tree.index[node.first : node.first+node.count] {
+				if j == i {
+					continue
+				}
+				d := x.Sub(pos[j])
+				soft := d.Dot(d) + eps2
+				inv := 1 / math.Sqrt(soft)
+				a = a.Sub(d.Mul(bh.G * mass[j] * inv * inv * inv))
+				phi -= bh.G * mass[j] * inv
+			}
+			continue
+		}
+
+		r := x.Sub(node.com)
+		dist := r.Abs()
+		if dist > 0 && 2*node.half < bh.Theta*dist && !node.contains(x) {
+			da, dphi := bh.multipole(node, r, dist, eps2)
+			a = a.Add(da)
+			phi += dphi
+			continue
+		}
+		for _, c := range node.child {
+			if c >= 0 {
+				s = append(s, c)
+			}
+		}
+	}
+	return a, phi
+}
+
+// multipole은 질량중심에서 r (|r| = dist)만큼 떨어진 점에서 노드의 다중극 가속도와 퍼텐셜입니다.
+func (bh *BarnesHut) multipole(node *octNode, r Vector, dist, eps2 float64) (Vector, float64) {
+	soft := dist*dist + eps2
+	inv := 1 / math.Sqrt(soft)
+	a := r.Mul(-bh.G * node.mass * inv * inv * inv)
+	phi := -bh.G * node.mass * inv
+	if !bh.Quadrupole {
+		return a, phi
+	}
+
+	Qr := node.quad.DotV(r)
+	rQr := r.Dot(Qr)
+	inv2 := 1 / (dist * dist)
+	inv5 := inv2 * inv2 / dist
+	a = a.Add(Qr.Mul(bh.G * inv5)).Sub(r.Mul(2.5 * bh.G * rQr * inv5 * inv2))
+	phi -= 0.5 * bh.G * rQr * inv5
+	return a, phi
+}
+
+// Accelerations는 ForceField 인터페이스 구현입니다. Potential도 갱신합니다.
+func (bh *BarnesHut) Accelerations(sim *Simulator) []Vector {
+	acc, pot := bh.Compute(sim)
+	bh.Potential = pot
+	return acc
+}
+
+// PotentialEnergy는 PotentialField 인터페이스 구현입니다: U = ½ Σ m_i Φ_i.
+func (bh *BarnesHut) PotentialEnergy(sim *Simulator) float64 {
+	_, pot := bh.Compute(sim)
+	U := 0.0
+	for i, phi := range pot {
+		U += 0.5 * sim.Mass[i] * phi
+	}
+	return U
+}
+
+// ── 팔분트리 ─────────────────────────────────────────────────────────────────
+
+// octreeMaxDepth는 트리의 최대 깊이입니다. 겹친 파티클이 LeafSize보다 많아도
+// 이 깊이에서 잎으로 끝나 분할이 무한히 반복되지 않습니다.
+const octreeMaxDepth = 48
+
+// octNode는 팔분트리의 노드입니다. 파티클은 octree.index[first : first+count]에 연속으로 놓입니다.
+type octNode struct {
+	center Vector  // 정육면체 중심
+	half   float64 // 반변 길이
+
+	mass float64 // 총 질량
+	com  Vector  // 질량중심
+	quad Tensor  // 질량중심 기준 무대각합 사중극 Σ m(3dd - d²I)
+
+	first, count int
+	child        [8]int // 자식 노드 인덱스 (-1: 비어 있음), 옥탄트 = x + 2y + 4z 비트
+	leaf         bool
+}
+
+// contains는 x가 노드 정육면체 안에 있는지 반환합니다.
+func (n *octNode) contains(x Vector) bool {
+	return math.Abs(x.X-n.center.X) <= n.half &&
+		math.Abs(x.Y-n.center.Y) <= n.half &&
+		math.Abs(x.Z-n.center.Z) <= n.half
+}
+
+// octree는 평탄한 노드 배열로 저장한 팔분트리입니다 (루트 = nodes[0]).
+type octree struct {
+	nodes   []octNode
+	index   []int // 노드 순서로 정렬된 파티클 인덱스
+	scratch []int // 분할용 작업 배열
+
+	pos      []Vector
+	mass     []float64
+	leafSize int
+}
+
+// buildOctree는 모든 파티클을 담는 정육면체에서 시작해 잎이 leafSize 이하가 될 때까지 분할합니다.
+func buildOctree(pos []Vector, mass []float64, leafSize int) *octree {
+	tree := &octree{}
+	tree.rebuild(pos, mass, leafSize)
+	return tree
+}
+
+// rebuild는 노드·인덱스 배열을 재사용해 트리를 처음부터 다시 만듭니다 (TreePM은 스텝마다 호출).
+func (t *octree) rebuild(pos []Vector, mass []float64, leafSize int) {
+	if leafSize < 1 {
+		leafSize = 1
+	}
+	N := len(pos)
+	t.nodes = t.nodes[:0]
+	t.index = resize(t.index, N)
+	t.scratch = resize(t.scratch, N)
+	t.pos, t.mass, t.leafSize = pos, mass, leafSize
+
+	lo, hi := pos[0], pos[0]
+	for i, r := range pos {
+		t.index[i] = i
+		lo = Vector{math.Min(lo.X, r.X), math.Min(lo.Y, r.Y), math.Min(lo.Z, r.Z)}
+		hi = Vector{math.Max(hi.X, r.X), math.Max(hi.Y, r.Y), math.Max(hi.Z, r.Z)}
+	}
+	size := math.Max(hi.X-lo.X, math.Max(hi.Y-lo.Y, hi.Z-lo.Z))
+	half := 0.5*size*(1+1e-12) + 1e-300
+	t.build(lo.Add(hi).Mul(0.5), half, 0, N, 0)
+}
+
+// build는 index[first : first+count]의 파티클로 노드를 만들고 그 인덱스를 반환합니다.
+func (t *octree) build(center Vector, half float64, first, count, depth int) int {
+	id := len(t.nodes)
+	t.nodes = append(t.nodes, octNode{center: center, half: half, first: first, count: count})
+	for c := range t.nodes[id].child {
+		t.nodes[id].child[c] = -1
+	}
+
+	if count <= t.leafSize || depth >= octreeMaxDepth {
+		t.nodes[id].leaf = true
+		t.leafMoments(id)
+		return id
+	}
+
+	// 옥탄트별 계수 정렬
+	var offsets [9]int
+	octant := func(r Vector) int {
+		o := 0
+		if r.X >= center.X {
+			o |= 1
+		}
+		if r.Y >= center.Y {
+			o |= 2
+		}
+		if r.Z >= center.Z {
+			o |= 4
+		}
+		return o
+	}
+	part := t.index[first : first+count]
+	for _, j := range part {
+		offsets[octant(t.pos[j])+1]++
+	}
+	for o := 0; o < 8; o++ {
+		offsets[o+1] += offsets[o]
+	}
+	cursor := offsets
+	for _, j := range part {
+		o := octant(t.pos[j])
+		t.scratch[first+cursor[o]] = j
+		cursor[o]++
+	}
+	copy(part, t.scratch[first:first+count])
+
+	for o := 0; o < 8; o++ {
+		n := offsets[o+1] - offsets[o]
+		if n == 0 {
+			continue
+		}
+		sign := func(bit int) float64 {
+			if o&bit != 0 {
+				return 0.5 * half
+			}
+			return -0.5 * half
+		}
+		c := center.Add(Vector{sign(1), sign(2), sign(4)})
+		child := t.build(c, 0.5*half, first+offsets[o], n, depth+1)
+		t.nodes[id].child[o] = child
+	}
+	t.childMoments(id)
+	return id
+}
+
+// leafMoments는 잎 노드의 질량, 질량중심, 사중극을 파티클에서 직접 계산합니다.
+func (t *octree) leafMoments(id int) {
+	node := &t.nodes[id]
+	part := t.index[node.first : node.first+node.count]
+	var weighted Vector
+	for _, j := range part {
+		node.mass += t.mass[j]
+		weighted = weighted.Add(t.pos[j].Mul(t.mass[j]))
+	}
+	node.com = node.center
+	if node.mass != 0 {
+		node.com = weighted.Div(node.mass)
+	}
+	for _, j := range part {
+		node.quad = node.quad.Add(quadrupole(t.pos[j].Sub(node.com), t.mass[j]))
+	}
+}
+
+// childMoments는 자식 노드의 모멘트를 평행축 정리로 합쳐 부모 모멘트를 만듭니다.
+func (t *octree) childMoments(id int) {
+	node := &t.nodes[id]
+	var weighted Vector
+	for _, c := range node.child {
+		if c >= 0 {
+			node.mass += t.nodes[c].mass
+			weighted = weighted.Add(t.nodes[c].com.Mul(t.nodes[c].mass))
+		}
+	}
+	node.com = node.center
+	if node.mass != 0 {
+		node.com = weighted.Div(node.mass)
+	}
+	for _, c := range node.child {
+		if c >= 0 {
+			child := &t.nodes[c]
+			node.quad = node.quad.Add(child.quad).Add(quadrupole(child.com.Sub(node.com), child.mass))
+		}
+	}
+}
+
+// quadrupole은 원점에서 d만큼 떨어진 질량 m의 무대각합 사중극 m(3dd - d²I)를 반환합니다.
+func quadrupole(d Vector, m float64) Tensor {
+	d2 := d.Dot(d)
+	return Tensor{
+		3*d.X*d.X - d2, 3 * d.X * d.Y, 3 * d.X * d.Z,
+		3 * d.Y * d.X, 3*d.Y*d.Y - d2, 3 * d.Y * d.Z,
+		3 * d.Z * d.X, 3 * d.Z * d.Y, 3*d.Z*d.Z - d2,
+	}.Mul(m)
+}
@@ -0,0 +1,90 @@
+package atom3D
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// plummerPositions는 Plummer 반경 a인 구형 성단에서 N개의 위치를 뽑습니다 (r < 10a에서 자름).
+func plummerPositions(N int, a float64, seed int64) []Vector {
+	rng := rand.New(rand.NewSource(seed))
+	pos := make([]Vector, 0, N)
+	for len(pos) < N {
+		r := a / math.Sqrt(math.Pow(rng.Float64(), -2./3.)-1)
+		if r > 10*a {
+			continue
+		}
+		cosTheta := 2*rng.Float64() - 1
+		sinTheta := math.Sqrt(1 - cosTheta*cosTheta)
+		phi := 2 * math.Pi * rng.Float64()
+		pos = append(pos, Vector{r * sinTheta * math.Cos(phi), r * sinTheta * math.Sin(phi), r * cosTheta})
+	}
+	return pos
+}
+
+// softenedDirect는 연화된 직접합 가속도와 퍼텐셜(단위 질량당)입니다.
+// 솔버 테스트의 독립 기준이므로 DirectSum을 쓰지 않는 단순 이중 루프입니다 (eps = 0이면 뉴턴 중력).
+func softenedDirect(sim *Simulator, G, eps float64) ([]Vector, []float64) {
+	acc := make([]Vector, sim.N)
+	pot := make([]float64, sim.N)
+	for i := range acc {
+		for j := range sim.Pos {
+			if i == j {
+				continue
+			}
+			d := sim.Pos[j].Sub(sim.Pos[i])
+			inv := 1 / math.Sqrt(d.Dot(d)+eps*eps)
+			acc[i] = acc[i].Add(d.Mul(G * sim.Mass[j] * inv * inv * inv))
+			pot[i] -= G * sim.Mass[j] * inv
+		}
+	}
+	return acc, pot
+}
+
+// Barnes–Hut 힘과 퍼텐셜을 직접합과 비교합니다.
+// θ = 0이면 모든 노드를 열어 직접합과 같아야 하고, 사중극은 단극보다 정확해야 합니다.
+func TestBarnesHut(t *testing.T) {
+	sim := ewaldSimulator(plummerPositions(1000, 1., 3), 0)
+	for i := range sim.Mass {
+		sim.Mass[i] = 1 + float64(i%3)
+	}
+	eps := 0.01
+	exact, exactPot := softenedDirect(sim, 1., eps)
+
+	bh := NewBarnesHut(1., 0, eps)
+	if err := CompareForces(bh.Accelerations(sim), exact); err.Max > 1e-12 {
+		t.Errorf("theta = 0: max error %.3e, want direct sum", err.Max)
+	}
+
+	rms := map[bool]float64{}
+	for _, quad := range []bool{false, true} {
+		bh := NewBarnesHut(1., 0.5, eps)
+		bh.Quadrupole = quad
+		rms[quad] = CompareForces(bh.Accelerations(sim), exact).RMS
+
+		U, want := 0., 0.
+		for i := range bh.Potential {
+			U += 0.5 * sim.Mass[i] * bh.Potential[i]
+			want += 0.5 * sim.Mass[i] * exactPot[i]
+		}
+		t.Logf("quadrupole %v: rms = %.3e, U = %.6f (direct %.6f)", quad, rms[quad], U, want)
+		if math.Abs(U-want) > 1e-3*math.Abs(want) {
+			t.Errorf("quadrupole %v: potential energy %v, want %v", quad, U, want)
+		}
+	}
+	if rms[true] > 2e-3 {
+		t.Errorf("theta = 0.5 quadrupole rms force error %.3e > 2e-3", rms[true])
+	}
+	if rms[true] > 0.5*rms[false] {
+		t.Errorf("quadrupole did not improve accuracy: %.3e -> %.3e", rms[false], rms[true])
+	}
+
+	// 겹친 파티클이 LeafSize보다 많아도 트리 생성이 끝나야 합니다.
+	stacked := ewaldSimulator(make([]Vector, 20), 0)
+	stacked.Pos[0] = Vector{1, 0, 0}
+	acc := NewBarnesHut(1., 0.5, eps).Accelerations(stacked)
+	if want := 19 / (1 + eps*eps) / math.Sqrt(1+eps*eps); math.Abs(acc[0].X+want) > 1e-9 {
+		t.Errorf("stacked particles: a = %v, want (-%v, 0, 0)", acc[0], want)
+	}
+}
@@ -6,8 +6,6 @@ import (
 	"math"
 	"net/http"
 	_ "net/http/pprof"
-	"runtime"
-	"sync"
 	"testing"
 
 	"gonum.org/v1/hdf5"
@@ -81,15 +79,8 @@ func (simulator SimulatorP3M_) Step() {
 
 	simulator.MakeGrid()
 
-	// PM 장거리 중력만 사용.
-	// p3m.go의 ppForce는 G*[erf(αr)-gaussian]/r² 커널을 쓰는데,
-	// 올바른 Ewald 단거리 보정(G*erfc(αr)/r²)과 달라서 PM+PP가
-	// r≈RCut 근방에서 중력을 ~2배로 만듦. PM만 써도 32³ 해상도에서는 충분.
-	gravity := simulator.p3m.PMForces(simulator.Pos)
-
-	numWorkers := runtime.NumCPU()
-	workChan := make(chan int, simulator.N)
-	var wg sync.WaitGroup
+	// P³M 중력: PM 장거리 + erfc 단거리 PP (MakeGrid는 위에서 호출)
+	gravity := simulator.p3m.ComputeForces(simulator.Simulator)
 
 	newVel := make([]Vector, simulator.N)
 	newPos := make([]Vector, simulator.N)
@@ -99,25 +90,16 @@ func (simulator SimulatorP3M_) Step() {
 	// 공변 포아송 보정: F_physical = F_PM / a
 	invA := 1.0 / (a * a) // F_peculiar = F_PM/a²  (du/dt = -H·u + F_PM/a²)
 
-	for w := 0; w < numWorkers; w++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for i := range workChan {
-				// F_comoving = F_PM / a  (comoving Poisson 1/a 보정)
-				f := gravity[i].Mul(invA)
-				// u_new = (u + F·dt) / (1 + H·dt)   [음해적 허블 마찰]
-				newVel[i] = simulator.Vel[i].Add(f.Mul(dt)).Mul(damp)
-				// x_new = x + u_new/a · dt
-				newPos[i] = simulator.Pos[i].Add(newVel[i].Mul(dt / a))
-			}
-		}()
-	}
-	for i := 0; i < simulator.N; i++ {
-		workChan <- i
-	}
-	close(workChan)
-	wg.Wait()
+	simulator.Parallel.For(simulator.N, func(_, lo, hi int) {
+		for i := lo; i < hi; i++ {
+			// F_comoving = F_PM / a  (comoving Poisson 1/a 보정)
+			f := gravity[i].Mul(invA)
+			// u_new = (u + F·dt) / (1 + H·dt)   [음해적 허블 마찰]
+			newVel[i] = simulator.Vel[i].Add(f.Mul(dt)).Mul(damp)
+			// x_new = x + u_new/a · dt
+			newPos[i] = simulator.Pos[i].Add(newVel[i].Mul(dt / a))
+		}
+	})
 
 	for i := 0; i < simulator.N; i++ {
 		simulator.Vel[i] = newVel[i]
@@ -255,8 +237,8 @@ func TestP3M(t *testing.T) {
 
 		if i%saveInterval == 0 {
 			// ── 밀도 장 진단 ─────────────────────────────────────────────
-			rho := simulator.p3m.AssignDensity(simulator.Pos)
-			meanRho := float64(N) / float64(simulator.p3m.Ng*simulator.p3m.Ng*simulator.p3m.Ng)
+			rho := simulator.p3m.AssignDensity(simulator.Pos, simulator.Mass)
+			meanRho := simulator.TotalMass() / float64(simulator.p3m.Ng*simulator.p3m.Ng*simulator.p3m.Ng)
 			maxRho := 0.0
 			for _, r := range rho {
 				if r > maxRho {
@@ -323,3 +305,489 @@ func TestP3M(t *testing.T) {
 		fmt.Printf("z = %6.4f  a = %.4f  T = %.4f\n", simulator.z, simulator.a, simulator.T)
 	}
 }
+
+// ── TestAssignDensityMass ────────────────────────────────────────────────────
+
+func TestAssignDensityMass(t *testing.T) {
+	p3m := NewP3M(8, 10., 1.)
+	pos := []Vector{{0.3, -1.2, 4.9}, {-4.99, 2.5, 0.}, {1., 1., 1.}}
+	mass := []float64{0.5, 2., 3.25}
+
+	total := 0.
+	for _, r := range p3m.AssignDensity(pos, mass) {
+		total += r
+	}
+	if math.Abs(total-5.75) > 1e-12 {
+		t.Errorf("assigned mass = %v, want 5.75", total)
+	}
+
+	total = 0.
+	for _, r := range p3m.AssignDensity(pos, nil) {
+		total += r
+	}
+	if math.Abs(total-3.) > 1e-12 {
+		t.Errorf("assigned unit mass = %v, want 3", total)
+	}
+}
+
+// ── TestCoulombP3M ───────────────────────────────────────────────────────────
+
+// 같은 부호 단위 전하·단위 질량이면 쿨롱 가속도는 중력 가속도의 부호 반전이어야 합니다.
+func TestCoulombP3M(t *testing.T) {
+	L := 10.
+	N := 64
+	pos := make([]Vector, N)
+	vel := make([]Vector, N)
+	id := make([]int, N)
+	for i := range pos {
+		pos[i] = Vector{
+			L * (math.Mod(0.618*float64(i), 1.) - 0.5),
+			L * (math.Mod(0.414*float64(i), 1.) - 0.5),
+			L * (math.Mod(0.732*float64(i), 1.) - 0.5),
+		}
+	}
+	sim := NewSimulator(0.01, id, pos, vel, Vector{})
+	sim.RegionSize = L
+	for i := range sim.Charge {
+		sim.Charge[i] = 1.
+	}
+
+	gravity := NewP3M(16, L, 1.)
+	coulomb := NewCoulombP3M(16, L, 1.)
+	sim.GridSize = gravity.RCut
+
+	gAcc := gravity.Accelerations(sim)
+	cAcc := coulomb.Accelerations(sim)
+	for i := range gAcc {
+		if d := cAcc[i].Add(gAcc[i]).Abs(); d > 1e-9*(1+gAcc[i].Abs()) {
+			t.Fatalf("particle %d: coulomb %v, gravity %v", i, cAcc[i], gAcc[i])
+		}
+	}
+
+	// q/m = -2 이면 가속도가 -2배가 됩니다.
+	for i := range sim.Charge {
+		sim.Charge[i] = -2.
+		sim.Mass[i] = 1.
+	}
+	aAcc := coulomb.Accelerations(sim)
+	for i := range aAcc {
+		if d := aAcc[i].Sub(cAcc[i].Mul(4)).Abs(); d > 1e-9*(1+aAcc[i].Abs()) {
+			t.Fatalf("particle %d: got %v, want %v", i, aAcc[i], cAcc[i].Mul(4))
+		}
+	}
+}
+
+// ── TestAssignmentSchemes ────────────────────────────────────────────────────
+
+func TestAssignmentSchemes(t *testing.T) {
+	// 가중치는 합이 1이고, p >= 2이면 1차 모멘트(질량 중심)를 보존합니다.
+	for _, a := range []Assignment{NGP, CIC, TSC, PCS} {
+		for _, g := range []float64{0, 0.2, 0.5, 0.73, 3.99, -1.3} {
+			i0, w := a.weights(g)
+			sum, moment := 0., 0.
+			for k := 0; k < a.Order(); k++ {
+				sum += w[k]
+				moment += w[k] * float64(i0+k)
+			}
+			if math.Abs(sum-1) > 1e-12 {
+				t.Errorf("order %d, g = %v: weight sum = %v", a.Order(), g, sum)
+			}
+			if a != NGP && math.Abs(moment-g) > 1e-12 {
+				t.Errorf("order %d, g = %v: first moment = %v", a.Order(), g, moment)
+			}
+		}
+	}
+
+}
+
+// ── TestOptimalInfluence ─────────────────────────────────────────────────────
+
+func TestOptimalInfluence(t *testing.T) {
+	// 정확도는 TestP3MModes에서 비교합니다. 캐시는 파라미터가 바뀔 때만 다시 계산합니다.
+	L := 10.
+	p3m := NewP3M(8, L, 1.)
+	first := p3m.optimalInfluence()
+	if &p3m.optimalInfluence()[0] != &first[0] {
+		t.Error("influence function was recomputed without parameter change")
+	}
+	p3m.Alpha *= 2
+	if &p3m.optimalInfluence()[0] == &first[0] {
+		t.Error("influence function was not recomputed after changing Alpha")
+	}
+}
+
+// ── TestGradientOperators ────────────────────────────────────────────────────
+
+func TestGradientOperators(t *testing.T) {
+	L := 10.
+	p3m := NewP3M(32, L, 1.)
+	dx := L / 32
+
+	// 작은 k에서 d(k) → k: FD2는 O(k³dx²), FD4는 O(k⁵dx⁴) 오차
+	k := Vector{0.3, -0.2, 0.1}
+	for _, c := range []struct {
+		g   Gradient
+		tol float64
+	}{{FD2, 0.3 * 0.3 * 0.3 * dx * dx}, {FD4, math.Pow(0.3, 5) * math.Pow(dx, 4)}, {Spectral, 1e-15}} {
+		p3m.Gradient = c.g
+		if d := p3m.gradientSymbol(k).Sub(k).Abs(); d > c.tol {
+			t.Errorf("gradient %d: |d(k) - k| = %.3e > %.3e", c.g, d, c.tol)
+		}
+	}
+}
+
+// ── TestInterlacing ──────────────────────────────────────────────────────────
+
+// 인터레이싱의 힘 정확도는 TestP3MModes에서 비교합니다.
+func TestInterlacing(t *testing.T) {
+	L := 10.
+	dx := L / 32
+	sim := ewaldSimulator(randomPositions(216, L, 7), L)
+
+	// 질량 0인 탐침에서 PMPotential은 Ewald 파수공간 포텐셜과 같아야 합니다.
+	//	Φ_k(x) = Σ_{k≠0} Σ_j -4π m_j/L³ · e^{-k²/4α²}/k² · cos(k·(x - r_j))
+	alpha := 0.5 / dx
+	probe := Vector{1.23, -0.45, 2.71}
+	want := 0.
+	dk := 2 * math.Pi / L
+	for nz := -8; nz <= 8; nz++ {
+		for ny := -8; ny <= 8; ny++ {
+			for nx := -8; nx <= 8; nx++ {
+				if nx == 0 && ny == 0 && nz == 0 {
+					continue
+				}
+				k := Vector{float64(nx) * dk, float64(ny) * dk, float64(nz) * dk}
+				g := -4 * math.Pi / (L * L * L) * math.Exp(-k.Dot(k)/(4*alpha*alpha)) / k.Dot(k)
+				for _, r := range sim.Pos {
+					want += g * math.Cos(k.Dot(probe.Sub(r)))
+				}
+			}
+		}
+	}
+	for _, interlaced := range []bool{false, true} {
+		p3m := NewP3M(32, L, 1.)
+		p3m.Interlaced = interlaced
+		p3m.Alpha = alpha
+		phi := p3m.PMPotential(append(append([]Vector{}, sim.Pos...), probe), append(append([]float64{}, sim.Mass...), 0))
+		t.Logf("interlaced %v: Φ_PM = %.6f, Ewald = %.6f", interlaced, phi[sim.N], want)
+		if math.Abs(phi[sim.N]-want) > 1e-2*math.Abs(want) {
+			t.Errorf("interlaced %v: Φ_PM = %v, want %v", interlaced, phi[sim.N], want)
+		}
+	}
+}
+
+// ── TestP3MModes ─────────────────────────────────────────────────────────────
+
+// P3M 옵션 하나를 바꿨을 때 EwaldSum 대비 힘 오차가 줄어드는지 확인합니다.
+// 한 배치에만 맞춘 결과가 되지 않도록 경우마다 파티클 수와 시드가 다릅니다.
+func TestP3MModes(t *testing.T) {
+	L := 10.
+	Ng := 32
+	dx := L / float64(Ng)
+	for _, c := range []struct {
+		name    string
+		N       int
+		seed    int64
+		alphaDx float64               // α·dx
+		set     func(p *P3M, on bool) // on = false: 기준, true: 비교 대상
+		ratio   float64               // 비교 대상 rms <= ratio · 기준 rms
+		limit   float64               // 비교 대상 rms 상한 (0이면 검사하지 않음)
+	}{
+		{"CIC/NGP", 180, 7, 0.5, func(p *P3M, on bool) { p.Assignment = pick(on, CIC, NGP) }, 0.35, 3e-2},
+		{"TSC/NGP", 216, 13, 0.5, func(p *P3M, on bool) { p.Assignment = pick(on, TSC, NGP) }, 0.35, 3e-2},
+		{"PCS/NGP", 250, 17, 0.5, func(p *P3M, on bool) { p.Assignment = pick(on, PCS, NGP) }, 0.35, 3e-2},
+		{"CIC/optimal", 200, 19, 0.5, func(p *P3M, on bool) { p.OptimalInfluence = on }, 0.7, 0},
+		{"TSC/optimal", 240, 23, 0.5, func(p *P3M, on bool) { p.Assignment, p.OptimalInfluence = TSC, on }, 0.7, 0},
+		{"FD4/FD2", 192, 29, 0.5, func(p *P3M, on bool) { p.Gradient = pick(on, FD4, FD2) }, 0.7, 0},
+		{"Spectral/FD2", 230, 31, 0.5, func(p *P3M, on bool) { p.Gradient = pick(on, Spectral, FD2) }, 0.7, 0},
+		// 앨리어싱이 주된 오차인 설정 (ik 미분, α·dx = 0.8)에서 인터레이싱은 오차를 크게 줄입니다.
+		{"interlaced", 210, 37, 0.8, func(p *P3M, on bool) { p.Gradient, p.Interlaced = Spectral, on }, 0.5, 0},
+	} {
+		sim := ewaldSimulator(randomPositions(c.N, L, c.seed), L)
+		exact := NewEwaldSum(L, 1.).Accelerations(sim)
+		rms := [2]float64{}
+		for o, on := range []bool{false, true} {
+			p3m := NewP3M(Ng, L, 1.)
+			c.set(p3m, on)
+			p3m.Alpha = c.alphaDx / dx
+			p3m.RCut = 3.2 / p3m.Alpha
+			sim.GridSize = p3m.RCut
+			rms[o] = CompareForces(p3m.Accelerations(sim), exact).RMS
+		}
+		t.Logf("%s (N = %d): rms = %.3e -> %.3e", c.name, c.N, rms[0], rms[1])
+		if rms[1] > c.ratio*rms[0] || (c.limit > 0 && rms[1] > c.limit) {
+			t.Errorf("%s: rms %.3e -> %.3e, want <= %.2f× (limit %v)", c.name, rms[0], rms[1], c.ratio, c.limit)
+		}
+	}
+}
+
+// pick은 on이면 a, 아니면 b를 반환합니다.
+func pick[T any](on bool, a, b T) T {
+	if on {
+		return a
+	}
+	return b
+}
+
+// ── TestIsolatedP3M ──────────────────────────────────────────────────────────
+
+// 고립 경계 P3M을 진공의 직접합 a_i = Σ_j m_j d/r³ (softenedDirect, ε = 0) 과 비교합니다.
+// 주기 P3M은 이미지 기여 때문에 같은 배치에서 오차가 훨씬 커야 합니다.
+func TestIsolatedP3M(t *testing.T) {
+	L := 10.
+	dx := L / 32
+	pos := []Vector{}
+	for _, r := range randomPositions(400, 0.8*L, 11) {
+		if r.Abs() < 0.4*L { // 중심 구 (지름 0.8L)
+			pos = append(pos, r)
+		}
+	}
+	sim := ewaldSimulator(pos, L)
+
+	exact, _ := softenedDirect(sim, 1., 0)
+
+	rms := map[bool]float64{}
+	for _, isolated := range []bool{true, false} {
+		p3m := NewP3M(32, L, 1.)
+		p3m.Isolated = isolated
+		p3m.Alpha = 0.5 / dx
+		p3m.RCut = 3.2 / p3m.Alpha
+		sim.GridSize = p3m.RCut
+		rms[isolated] = CompareForces(p3m.Accelerations(sim), exact).RMS
+	}
+	t.Logf("rms = %.3e (isolated), %.3e (periodic)", rms[true], rms[false])
+	if rms[true] > 2e-2 {
+		t.Errorf("isolated P3M rms force error %.3e > 2e-2", rms[true])
+	}
+	if rms[true] > 0.2*rms[false] {
+		t.Errorf("isolated mode is not closer to the vacuum sum than periodic (%.3e vs %.3e)", rms[true], rms[false])
+	}
+
+	// 진공에서 단일 소스의 PM 포텐셜은 -erf(αr)/r 입니다.
+	p3m := NewIsolatedP3M(32, L, 1.)
+	probe := Vector{2.1, -1.3, 0.7}
+	phi := p3m.PMPotential([]Vector{{-1.5, 0.4, -0.2}, probe}, []float64{1, 0})
+	r := probe.Sub(Vector{-1.5, 0.4, -0.2}).Abs()
+	want := -math.Erf(p3m.Alpha*r) / r
+	t.Logf("Φ_PM = %.6f, want %.6f", phi[1], want)
+	if math.Abs(phi[1]-want) > 1e-2*math.Abs(want) {
+		t.Errorf("isolated PM potential %v, want %v", phi[1], want)
+	}
+}
+
+// ── TestTreePM ───────────────────────────────────────────────────────────────
+
+// TreePM의 트리 PP 보정은 θ = 0이면 셀 격자 PP와 같고, θ = 0.5의 단극 근사는
+// 밀집된 헤일로에서도 전체 P3M 오차를 거의 늘리지 않아야 합니다.
+func TestTreePM(t *testing.T) {
+	L := 10.
+	dx := L / 32
+	pos := randomPositions(200, L, 9)
+	for _, r := range plummerPositions(600, 0.3, 9) { // 박스 안의 밀집 헤일로
+		if math.Abs(r.X) < L/2 && math.Abs(r.Y) < L/2 && math.Abs(r.Z) < L/2 {
+			pos = append(pos, r.Add(Vector{2, -3, 4.5})) // 경계를 넘는 헤일로
+		}
+	}
+	for i := range pos {
+		pos[i] = minimumImage(pos[i], L)
+	}
+	sim := ewaldSimulator(pos, L)
+	exact := NewEwaldSum(L, 1.).Accelerations(sim)
+
+	p3m := NewP3M(32, L, 1.)
+	p3m.Alpha = 0.5 / dx
+	p3m.RCut = 3.2 / p3m.Alpha
+	sim.GridSize = p3m.RCut
+	cell := CompareForces(p3m.Accelerations(sim), exact).RMS
+
+	p3m.TreePM = true
+	p3m.TreeTheta = 0
+	if err := CompareForces(p3m.PPCorrections(sim), (&P3M{Alpha: p3m.Alpha, RCut: p3m.RCut, L: L, G: 1}).PPCorrections(sim)); err.Max > 1e-12 {
+		t.Errorf("theta = 0: tree PP differs from cell PP (max %.3e)", err.Max)
+	}
+
+	p3m.TreeTheta = 0.5
+	tree := CompareForces(p3m.Accelerations(sim), exact).RMS
+	t.Logf("rms = %.3e (cell PP), %.3e (tree PP, θ = 0.5)", cell, tree)
+	if tree > 1.2*cell {
+		t.Errorf("tree PP increased the error: %.3e > 1.2 × %.3e", tree, cell)
+	}
+}
+
+// P3M 퍼텐셜을 Madelung 상수, 수렴한 기준 해, 고립 경계 직접합과 비교합니다.
+func TestP3MPotential(t *testing.T) {
+	L := 10.
+	solver := func(ng int, assignment Assignment, alphaDx float64) *P3M {
+		p3m := NewP3M(ng, L, 1.)
+		p3m.Assignment = assignment
+		p3m.Alpha = alphaDx * float64(ng) / L
+		p3m.RCut = 3.2 / p3m.Alpha
+		return p3m
+	}
+	relative := func(a, b []float64) float64 {
+		diff, norm := 0., 0.
+		for i := range a {
+			diff += (a[i] - b[i]) * (a[i] - b[i])
+			norm += b[i] * b[i]
+		}
+		return math.Sqrt(diff / norm)
+	}
+
+	// 균일 배경 속 단일 파티클 (단순입방 Wigner 결정): Φ = -G·m·ξ/L,  ξ = -2.837297
+	single := ewaldSimulator([]Vector{{0.3, -1.1, 2.0}}, L)
+	p3m := solver(32, TSC, 0.5)
+	single.GridSize = p3m.RCut
+	p3m.Accelerations(single)
+	if want := 2.837297 / L; math.Abs(p3m.Potential[0]-want) > 1e-2*want {
+		t.Errorf("single particle Φ = %.6f, want Madelung %.6f", p3m.Potential[0], want)
+	}
+
+	// 자기 항과 배경 항은 α와 격자에 따라 바뀌는 PM/PP 분배를 상쇄해야 합니다.
+	sim := ewaldSimulator(randomPositions(500, L, 21), L)
+	for i := range sim.Mass {
+		sim.Mass[i] = 1 + float64(i%4)
+	}
+	run := func(p3m *P3M) ([]float64, float64) {
+		sim.GridSize = p3m.RCut
+		p3m.Accelerations(sim)
+		return p3m.Potential, p3m.PotentialEnergy(sim)
+	}
+	refPhi, refU := run(solver(64, PCS, 0.5))
+	for _, c := range []struct {
+		assignment Assignment
+		alphaDx    float64
+		tol        float64
+	}{{TSC, 0.5, 3e-3}, {TSC, 0.7, 1e-2}, {CIC, 0.5, 3e-2}} {
+		phi, U := run(solver(32, c.assignment, c.alphaDx))
+		err := relative(phi, refPhi)
+		t.Logf("assignment %d, αdx = %.1f: rms Φ error %.3e, U = %.4f (reference %.4f)", c.assignment, c.alphaDx, err, U, refU)
+		if err > c.tol {
+			t.Errorf("assignment %d, αdx = %.1f: rms potential error %.3e > %.0e", c.assignment, c.alphaDx, err, c.tol)
+		}
+		if math.Abs(U-refU) > c.tol*math.Abs(refU) {
+			t.Errorf("assignment %d, αdx = %.1f: potential energy %v, want %v", c.assignment, c.alphaDx, U, refU)
+		}
+	}
+
+	// TreePM (TreeTheta = 0)은 셀 격자 PP와 같은 퍼텐셜을 줘야 합니다.
+	tree := solver(32, CIC, 0.5)
+	cellPhi, _ := run(tree)
+	tree.TreePM = true
+	tree.TreeTheta = 0
+	if treePhi, _ := run(tree); relative(treePhi, cellPhi) > 1e-12 {
+		t.Errorf("TreePM potential differs from cell PP: %.3e", relative(treePhi, cellPhi))
+	}
+
+	// 고립 경계: 진공 직접합 퍼텐셜
+	pos := []Vector{}
+	for _, r := range randomPositions(400, 0.8*L, 11) {
+		if r.Abs() < 0.4*L {
+			pos = append(pos, r)
+		}
+	}
+	vacuum := ewaldSimulator(pos, L)
+	_, exactPhi := softenedDirect(vacuum, 1., 0)
+	want := 0.
+	for i, phi := range exactPhi {
+		want += 0.5 * vacuum.Mass[i] * phi
+	}
+	iso := NewIsolatedP3M(32, L, 1.)
+	iso.Assignment = TSC
+	vacuum.GridSize = iso.RCut
+	iso.Accelerations(vacuum)
+	U := iso.PotentialEnergy(vacuum)
+	t.Logf("isolated: rms Φ error %.3e, U = %.4f (direct %.4f)", relative(iso.Potential, exactPhi), U, want)
+	if err := relative(iso.Potential, exactPhi); err > 5e-3 {
+		t.Errorf("isolated P3M rms potential error %.3e > 5e-3", err)
+	}
+	if math.Abs(U-want) > 1e-3*math.Abs(want) {
+		t.Errorf("isolated P3M potential energy %v, want %v", U, want)
+	}
+}
+
+// AddAccelerations는 작업 버퍼를 재사용하면서 Accelerations와 같은 가속도·퍼텐셜을 더해야 하고,
+// 정상 상태에서는 (워커 하나에서) 아무것도 할당하지 않아야 합니다.
+func TestP3MAddAccelerations(t *testing.T) {
+	L := 10.
+	for _, c := range []struct {
+		name  string
+		setup func(p3m *P3M)
+	}{
+		{"default", func(p3m *P3M) {}},
+		{"interlaced spectral TSC", func(p3m *P3M) { p3m.Interlaced, p3m.Gradient, p3m.Assignment = true, Spectral, TSC }},
+		{"optimal FD4", func(p3m *P3M) { p3m.OptimalInfluence, p3m.Gradient = true, FD4 }},
+		{"TreePM", func(p3m *P3M) { p3m.TreePM = true }},
+		{"isolated", func(p3m *P3M) { p3m.Isolated = true }},
+	} {
+		sim := ewaldSimulator(randomPositions(800, 0.8*L, 5), L)
+		sim.Parallel = Parallel{Workers: 1}
+		p3m := NewP3M(32, L, 1.)
+		c.setup(p3m)
+		sim.GridSize = p3m.RCut
+
+		want := p3m.Accelerations(sim)
+		wantPhi := p3m.Potential
+		offset := Vector{1, -2, 3}
+		acc := make([]Vector, sim.N)
+		for i := range acc {
+			acc[i] = offset
+		}
+		p3m.AddAccelerations(sim, acc)
+		for i := range acc {
+			if d := acc[i].Sub(offset).Sub(want[i]).Abs(); d > 1e-12*(1+want[i].Abs()) {
+				t.Fatalf("%s: particle %d acceleration differs by %.3e", c.name, i, d)
+			}
+			if p3m.Potential[i] != wantPhi[i] {
+				t.Fatalf("%s: particle %d potential %v, want %v", c.name, i, p3m.Potential[i], wantPhi[i])
+			}
+		}
+
+		if allocs := testing.AllocsPerRun(3, func() { p3m.AddAccelerations(sim, acc) }); allocs != 0 {
+			t.Errorf("%s: %.0f allocs per AddAccelerations, want 0", c.name, allocs)
+		}
+	}
+}
+
+func BenchmarkP3MAddAccelerations(b *testing.B) {
+	L := 10.
+	sim := ewaldSimulator(perturbedLattice(32, L, 0.5, 3), L)
+	p3m := NewP3M(64, L, 1.)
+	sim.GridSize = p3m.RCut
+	acc := make([]Vector, sim.N)
+	p3m.AddAccelerations(sim, acc)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p3m.AddAccelerations(sim, acc)
+	}
+}
+
+// 영역 경계 ±L/2 위의 파티클도 할당 스텐실이 격자 반대편으로 감기지 않아야 합니다.
+// 감기면 맞은편 면의 파티클이 겹친 이미지를 보게 되어 서로 당기는 힘이 사라집니다.
+func TestIsolatedP3MEdges(t *testing.T) {
+	L := 10.
+	edge := math.Nextafter(L/2, 0) // [-L/2, L/2) 안의 가장 큰 값
+	pairs := [][2]Vector{
+		{{-L / 2, 0.3, -1.1}, {edge, 0.3, -1.1}},       // 마주 보는 면
+		{{-L / 2, -L / 2, 2.2}, {edge, edge, 2.2}},     // 마주 보는 모서리
+		{{-L / 2, -L / 2, -L / 2}, {edge, edge, edge}}, // 마주 보는 꼭짓점
+		{{1.7, -L / 2, edge}, {-0.4, edge, -L / 2}},    // 서로 다른 두 축의 경계
+	}
+	for _, a := range []Assignment{CIC, TSC, PCS} {
+		for _, g := range []Gradient{FD2, FD4} {
+			for _, interlaced := range []bool{false, true} {
+				p3m := NewIsolatedP3M(16, L, 1.)
+				p3m.Assignment, p3m.Gradient, p3m.Interlaced = a, g, interlaced
+				for _, pair := range pairs {
+					got := p3m.PMForces(pair[:], []float64{1, 1})[0] // 거리 >= L > RCut 이므로 PP 기여 없음
+					d := pair[1].Sub(pair[0])
+					want := d.Mul(1 / math.Pow(d.Abs(), 3))
+					if e := got.Sub(want).Abs() / want.Abs(); e > 2e-2 {
+						t.Errorf("order %d, gradient %d, interlaced %v: %v -> %v: acc %v, want %v (error %.1f%%)",
+							a.Order(), g, interlaced, pair[0], pair[1], got, want, 100*e)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,547 @@
+package atom3D
+
+import (
+	"math"
+	"reflect"
+	"slices"
+)
+
+// PairPotential은 거리 r에만 의존하는 단거리 쌍 퍼텐셜입니다.
+//
+//	Energy(r) : U(r)
+//	Force(r)  : F(r) = -dU/dr  (양수 = 척력)
+type PairPotential interface {
+	Energy(r float64) float64
+	Force(r float64) float64
+}
+
+// naturalCutoff는 고유 컷오프를 가진 퍼텐셜(WCA 등)이 구현합니다.
+// PairForce.RCut이 0이면 이 값을 사용합니다.
+type naturalCutoff interface {
+	Cutoff() float64
+}
+
+// TailCorrector는 컷오프 밖 꼬리 보정 적분을 해석적으로 제공하는 퍼텐셜이 구현합니다.
+//
+//	energy = ∫_rc^∞ U(r)·4πr² dr
+//	virial = ∫_rc^∞ r·F(r)·4πr² dr
+type TailCorrector interface {
+	TailIntegrals(rc float64) (energy, virial float64)
+}
+
+// ── 해석적 퍼텐셜 ────────────────────────────────────────────────────────────
+
+// LennardJones: U = 4ε[(σ/r)¹² - (σ/r)⁶]
+type LennardJones struct {
+	Epsilon float64
+	Sigma   float64
+}
+
+func (lj LennardJones) Energy(r float64) float64 {
+	s6 := math.Pow(lj.Sigma/r, 6)
+	return 4 * lj.Epsilon * (s6*s6 - s6)
+}
+
+func (lj LennardJones) Force(r float64) float64 {
+	s6 := math.Pow(lj.Sigma/r, 6)
+	return 24 * lj.Epsilon * (2*s6*s6 - s6) / r
+}
+
+func (lj LennardJones) TailIntegrals(rc float64) (float64, float64) {
+	x3 := math.Pow(lj.Sigma/rc, 3)
+	x9 := x3 * x3 * x3
+	s3 := lj.Sigma * lj.Sigma * lj.Sigma
+	energy := 16 * math.Pi * lj.Epsilon * s3 * (x9/9 - x3/3)
+	virial := 16 * math.Pi * lj.Epsilon * s3 * (4*x9/3 - 2*x3)
+	return energy, virial
+}
+
+// LorentzBerthelot은 두 LJ 파라미터의 혼합 규칙 결과를 반환합니다.
+//
+//	σ_ab = (σ_a + σ_b)/2,  ε_ab = √(ε_a·ε_b)
+func LorentzBerthelot(a, b LennardJones) LennardJones {
+	return LennardJones{
+		Epsilon: math.Sqrt(a.Epsilon * b.Epsilon),
+		Sigma:   (a.Sigma + b.Sigma) / 2,
+	}
+}
+
+// WCA(Weeks-Chandler-Andersen): LJ를 최소점 r = 2^(1/6)σ 에서 자르고 ε만큼 올린 순수 척력 퍼텐셜.
+type WCA struct {
+	Epsilon float64
+	Sigma   float64
+}
+
+func (w WCA) Cutoff() float64 {
+	return math.Pow(2, 1./6.) * w.Sigma
+}
+
+func (w WCA) Energy(r float64) float64 {
+	if r >= w.Cutoff() {
+		return 0
+	}
+	return LennardJones{w.Epsilon, w.Sigma}.Energy(r) + w.Epsilon
+}
+
+func (w WCA) Force(r float64) float64 {
+	if r >= w.Cutoff() {
+		return 0
+	}
+	return LennardJones{w.Epsilon, w.Sigma}.Force(r)
+}
+
+// Morse: U = D[(1 - e^{-a(r-r0)})² - 1]
+type Morse struct {
+	D  float64 // 결합 에너지
+	A  float64 // 폭 파라미터 [1/length]
+	R0 float64 // 평형 거리
+}
+
+func (m Morse) Energy(r float64) float64 {
+	e := math.Exp(-m.A * (r - m.R0))
+	return m.D * ((1-e)*(1-e) - 1)
+}
+
+func (m Morse) Force(r float64) float64 {
+	e := math.Exp(-m.A * (r - m.R0))
+	return -2 * m.D * m.A * e * (1 - e)
+}
+
+// Yukawa(차폐 쿨롱): U = A·e^{-κr}/r
+type Yukawa struct {
+	A     float64
+	Kappa float64 // 차폐 파수 [1/length]
+}
+
+func (y Yukawa) Energy(r float64) float64 {
+	return y.A * math.Exp(-y.Kappa*r) / r
+}
+
+func (y Yukawa) Force(r float64) float64 {
+	return y.A * math.Exp(-y.Kappa*r) * (1 + y.Kappa*r) / (r * r)
+}
+
+func (y Yukawa) TailIntegrals(rc float64) (float64, float64) {
+	k := y.Kappa
+	e := math.Exp(-k * rc)
+	energy := 4 * math.Pi * y.A * e * (1 + k*rc) / (k * k)
+	virial := 4 * math.Pi * y.A * e * (3 + 3*k*rc + k*k*rc*rc) / (k * k)
+	return energy, virial
+}
+
+// SoftSphere: U = ε(σ/r)^n
+type SoftSphere struct {
+	Epsilon float64
+	Sigma   float64
+	N       int
+}
+
+func (s SoftSphere) Energy(r float64) float64 {
+	return s.Epsilon * math.Pow(s.Sigma/r, float64(s.N))
+}
+
+func (s SoftSphere) Force(r float64) float64 {
+	return float64(s.N) * s.Energy(r) / r
+}
+
+// TailIntegrals는 n > 3 일 때만 유한합니다.
+func (s SoftSphere) TailIntegrals(rc float64) (float64, float64) {
+	n := float64(s.N)
+	energy := 4 * math.Pi * s.Epsilon * math.Pow(s.Sigma, n) * math.Pow(rc, 3-n) / (n - 3)
+	return energy, n * energy
+}
+
+// ── 컷오프 처리 ──────────────────────────────────────────────────────────────
+
+// ShiftMode는 컷오프에서의 불연속 처리 방식입니다.
+type ShiftMode int
+
+const (
+	NoShift     ShiftMode = iota // 단순 절단 (U, F 모두 rc에서 불연속)
+	EnergyShift                  // U(r) - U(rc)  (U 연속, F 불연속)
+	ForceShift                   // U(r) - U(rc) + (r-rc)·F(rc),  F(r) - F(rc)  (U, F 모두 연속)
+)
+
+// ── PairForce ────────────────────────────────────────────────────────────────
+
+// PairForce는 셀 목록(MakeGrid/ForEachNeighbor) 위에서 쌍 퍼텐셜의 힘, 파티클별 에너지,
+// 비리얼을 병렬로 계산하는 ForceField입니다.
+//
+// 종(Species)별 퍼텐셜은 SetPair로 지정하며, 지정되지 않은 쌍은 Potential을 사용합니다.
+// 계산마다 실제로 있는 종 Id끼리의 표(컷오프, 시프트 상수 포함)를 한 번 만들어 쌍마다 map을 조회하지 않습니다.
+// Neighbors를 지정하면 셀 목록 대신 Verlet 이웃 목록을 읽습니다 (NeighborList).
+//
+// 사전 조건: sim.RegionSize 설정, sim.GridSize >= RCut (이웃 누락 방지)
+type PairForce struct {
+	Potential      PairPotential // 기본 퍼텐셜
+	RCut           float64       // 컷오프 반경 (0이면 퍼텐셜 고유 컷오프)
+	Shift          ShiftMode     // 컷오프 처리 방식
+	TailCorrection bool          // 에너지·비리얼 꼬리 보정 (균일 밀도 가정)
+	Periodic       bool          // 주기 경계 최소 이미지 사용
+
+	// Neighbors가 nil이 아니면 이웃을 이 Verlet 목록에서 읽습니다.
+	// Cutoff >= 모든 쌍의 컷오프, Periodic = pf.Periodic 으로 만들어야 합니다.
+	Neighbors *NeighborList
+
+	// Energy는 마지막 힘 계산의 파티클별 퍼텐셜 에너지입니다.
+	// AddAccelerations는 이 배열을 재사용하므로 보관하려면 복사하세요.
+	Energy []float64
+	Virial float64 // 마지막 계산의 비리얼 W = Σ_pairs r·F(r) (꼬리 보정 포함)
+
+	pairs        map[[2]int]PairPotential
+	pairsVersion int // SetPair 호출 횟수 (PotentialEnergy의 재사용 판단)
+
+	// 계산마다 재사용하는 작업 버퍼
+	table      []pairEntry // 종×종 퍼텐셜 표 (resolve)
+	ids        []int       // 표의 종 Id (있는 Id만, 오름차순)
+	speciesOf  []int       // 파티클별 ids 인덱스
+	virials    []float64   // 워커별 비리얼
+	counts     []float64   // 종별 파티클 수 (꼬리 보정)
+	tailEnergy []float64   // 종별 꼬리 에너지
+	energyPos  []Vector    // Energy를 계산한 위치 (PotentialEnergy)
+	energyIds  []int       // Energy를 계산한 종 Id
+	energyKey  energyKey   // Energy를 계산한 파라미터
+	scratch    []float64   // PotentialEnergy의 에너지 전용 계산
+	loop       pairLoop    // compute의 병렬 루프 몸체 (Parallel.run)
+}
+
+// energyKey는 Energy를 계산할 때의 파라미터입니다. 하나라도 바뀌면 Energy를 재사용하지 않습니다.
+type energyKey struct {
+	potential      PairPotential
+	rCut           float64
+	shift          ShiftMode
+	tailCorrection bool
+	periodic       bool
+	pairsVersion   int
+	regionSize     float64 // 최소 이미지, 꼬리 보정 밀도
+}
+
+// pairEntry는 종 쌍 하나의 퍼텐셜, 컷오프, 시프트 상수입니다.
+type pairEntry struct {
+	pot    PairPotential
+	rc     float64
+	uc, fc float64 // U(rc), F(rc) (Shift != NoShift일 때)
+}
+
+// NewPairForce는 기본 퍼텐셜과 컷오프로 PairForce를 생성합니다.
+func NewPairForce(potential PairPotential, rCut float64) *PairForce {
+	return &PairForce{
+		Potential: potential,
+		RCut:      rCut,
+		Shift:     NoShift,
+		pairs:     map[[2]int]PairPotential{},
+	}
+}
+
+// SetPair는 종 a, b 사이의 퍼텐셜을 지정합니다 (대칭).
+func (pf *PairForce) SetPair(a, b int, potential PairPotential) {
+	if pf.pairs == nil {
+		pf.pairs = map[[2]int]PairPotential{}
+	}
+	pf.pairs[[2]int{a, b}] = potential
+	pf.pairs[[2]int{b, a}] = potential
+	pf.pairsVersion++
+}
+
+func (pf *PairForce) potential(a, b int) PairPotential {
+	if pot, ok := pf.pairs[[2]int{a, b}]; ok {
+		return pot
+	}
+	return pf.Potential
+}
+
+// cutoff는 퍼텐셜에 적용되는 컷오프 반경입니다.
+func (pf *PairForce) cutoff(pot PairPotential) float64 {
+	if pf.RCut > 0 {
+		return pf.RCut
+	}
+	if nc, ok := pot.(naturalCutoff); ok {
+		return nc.Cutoff()
+	}
+	return math.Inf(1)
+}
+
+// entry는 퍼텐셜 pot을 컷오프 rc에서 자른 표 항목을 만듭니다.
+func (pf *PairForce) entry(pot PairPotential, rc float64) pairEntry {
+	e := pairEntry{pot: pot, rc: rc}
+	if pf.Shift != NoShift {
+		e.uc, e.fc = pot.Energy(rc), pot.Force(rc)
+	}
+	return e
+}
+
+// resolve는 sim에 있는 서로 다른 종 Id S개로 S×S 표를 만듭니다 (계산마다 한 번, O(N log S + S²)).
+// Id 값의 범위가 아니라 개수로 표를 잡으므로 0과 100000처럼 떨어진 Id도 표는 2×2입니다.
+// 필드(Potential, RCut, Shift)나 SetPair를 스텝 사이에 바꿔도 다음 계산에 반영됩니다.
+func (pf *PairForce) resolve(sim *Simulator) {
+	ids := sim.Id[:sim.N]
+	pf.ids = pf.ids[:0]
+	for _, id := range ids {
+		if k, found := slices.BinarySearch(pf.ids, id); !found {
+			pf.ids = slices.Insert(pf.ids, k, id)
+		}
+	}
+	pf.speciesOf = resize(pf.speciesOf, sim.N)
+	for i, id := range ids {
+		pf.speciesOf[i], _ = slices.BinarySearch(pf.ids, id)
+	}
+
+	S := len(pf.ids)
+	pf.table = resize(pf.table, S*S)
+	for a, ida := range pf.ids {
+		for b, idb := range pf.ids {
+			pot := pf.potential(ida, idb)
+			pf.table[a*S+b] = pf.entry(pot, pf.cutoff(pot))
+		}
+	}
+}
+
+// lookup은 파티클 i, j의 종 쌍 표 항목입니다 (resolve 이후).
+func (pf *PairForce) lookup(i, j int) *pairEntry {
+	return &pf.table[pf.speciesOf[i]*len(pf.ids)+pf.speciesOf[j]]
+}
+
+// eval은 시프트를 적용한 U(r), F(r)을 반환합니다. r >= rc이면 0입니다.
+func (e *pairEntry) eval(r float64, shift ShiftMode) (float64, float64) {
+	if r >= e.rc {
+		return 0, 0
+	}
+	u, f := e.pot.Energy(r), e.pot.Force(r)
+	switch shift {
+	case EnergyShift:
+		u -= e.uc
+	case ForceShift:
+		u = u - e.uc + (r-e.rc)*e.fc
+		f -= e.fc
+	}
+	return u, f
+}
+
+// eval은 퍼텐셜 pot을 rc에서 자르고 시프트를 적용한 U(r), F(r)을 반환합니다 (표 없이).
+func (pf *PairForce) eval(pot PairPotential, r, rc float64) (float64, float64) {
+	e := pf.entry(pot, rc)
+	return e.eval(r, pf.Shift)
+}
+
+// displacement는 i→j 변위입니다 (Periodic이면 최소 이미지).
+func (pf *PairForce) displacement(sim *Simulator, i, j int) Vector {
+	if pf.Periodic {
+		return sim.PeriodicDisplacement(i, j)
+	}
+	return sim.Pos[j].Sub(sim.Pos[i])
+}
+
+// pair는 파티클 i가 이웃 j에게서 받는 힘과, 쌍 에너지·비리얼 중 i의 몫(½U, ½rF)을 반환합니다.
+// 거리가 0인 쌍은 기여하지 않습니다.
+func (pf *PairForce) pair(sim *Simulator, i, j int) (Vector, float64, float64) {
+	e := pf.lookup(i, j)
+	d := pf.displacement(sim, i, j)
+	r := d.Abs()
+	if r == 0 {
+		return Vector{}, 0, 0 // 겹친 파티클(또는 자기 이미지)은 방향이 없으므로 건너뜁니다 (P3M PP와 같음).
+	}
+	u, fr := e.eval(r, pf.Shift)
+	if fr == 0 && u == 0 {
+		return Vector{}, 0, 0
+	}
+	// 척력(F>0)은 i를 j 반대 방향(-d)으로 밉니다.
+	return d.Mul(-fr / r), 0.5 * u, 0.5 * r * fr
+}
+
+// Compute는 가속도, 파티클별 에너지, 비리얼을 새 배열로 계산합니다.
+// 각 쌍의 에너지와 비리얼은 두 파티클에 반씩 나눠 집계합니다.
+func (pf *PairForce) Compute(sim *Simulator) ([]Vector, []float64, float64) {
+	acc := make([]Vector, sim.N)
+	energy := make([]float64, sim.N)
+	virial := pf.compute(sim, acc, energy)
+	return acc, energy, virial
+}
+
+// compute는 Compute의 본체입니다. 가속도를 acc에 더하고 (nil이면 건너뜀) 파티클별 에너지를
+// energy에 쓰며 비리얼을 반환합니다. 작업 버퍼를 재사용하므로 배열을 새로 할당하지 않습니다.
+func (pf *PairForce) compute(sim *Simulator, acc []Vector, energy []float64) float64 {
+	N := sim.N
+	pf.resolve(sim)
+
+	// 셀 순서로 돌아 이웃 셀을 캐시에 유지합니다.
+	var order []int
+	if pf.Neighbors != nil {
+		pf.Neighbors.Update(sim)
+		order = pf.Neighbors.Order()
+	} else {
+		sim.MakeGrid()
+		order = sim.ParticleIndex
+	}
+
+	pf.virials = resize(pf.virials, sim.Parallel.workers())
+	clear(pf.virials)
+	pf.loop = pairLoop{pf: pf, sim: sim, order: order, acc: acc, energy: energy}
+	sim.Parallel.run(N, &pf.loop)
+
+	virial := 0.0
+	for _, v := range pf.virials {
+		virial += v
+	}
+	if pf.TailCorrection {
+		virial += pf.addTail(sim, energy)
+	}
+	return virial
+}
+
+// pairLoop은 compute의 파티클별 병렬 루프입니다 (order 순서, 비리얼은 워커별 pf.virials에 누적).
+type pairLoop struct {
+	pf     *PairForce
+	sim    *Simulator
+	order  []int
+	acc    []Vector
+	energy []float64
+}
+
+func (l *pairLoop) run(w, lo, hi int) {
+	pf, sim := l.pf, l.sim
+	var cells [64]int
+	for _, i := range l.order[lo:hi] {
+		var f Vector
+		var u, vir float64
+		if pf.Neighbors != nil {
+			for _, j := range pf.Neighbors.Of(i) {
+				df, du, dw := pf.pair(sim, i, j)
+				f, u, vir = f.Add(df), u+du, vir+dw
+			}
+		} else {
+			for _, c := range sim.neighborCells(i, pf.Periodic, &cells) {
+				for _, j := range sim.Cell(c) {
+					if j == i {
+						continue
+					}
+					df, du, dw := pf.pair(sim, i, j)
+					f, u, vir = f.Add(df), u+du, vir+dw
+				}
+			}
+		}
+		if l.acc != nil {
+			l.acc[i] = l.acc[i].Add(f.Div(sim.Mass[i]))
+		}
+		l.energy[i] = u
+		pf.virials[w] += vir
+	}
+}
+
+// addTail은 균일 밀도(ρ_b = N_b / RegionSize³)를 가정한 파티클별 꼬리 에너지를 energy에 더하고
+// 전체 꼬리 비리얼을 반환합니다 (resolve 이후).
+//
+//	E_i    = ½ Σ_b ρ_b ∫_rc^∞ U_ab 4πr² dr
+//	W_tail = ½ Σ_a Σ_b N_a ρ_b ∫_rc^∞ r F_ab 4πr² dr
+func (pf *PairForce) addTail(sim *Simulator, energy []float64) float64 {
+	V := sim.RegionSize * sim.RegionSize * sim.RegionSize
+	S := len(pf.ids)
+	pf.counts = resize(pf.counts, S)
+	pf.tailEnergy = resize(pf.tailEnergy, S)
+	count, perSpecies := pf.counts, pf.tailEnergy
+	clear(count)
+	clear(perSpecies)
+	for i := 0; i < sim.N; i++ {
+		count[pf.speciesOf[i]]++
+	}
+
+	virial := 0.0
+	for a, Na := range count {
+		for b, Nb := range count {
+			if Na == 0 || Nb == 0 {
+				continue
+			}
+			e := &pf.table[a*S+b]
+			tc, ok := e.pot.(TailCorrector)
+			if !ok {
+				continue
+			}
+			u, w := tc.TailIntegrals(e.rc)
+			rhoB := Nb / V
+			perSpecies[a] += 0.5 * rhoB * u
+			virial += 0.5 * Na * rhoB * w
+		}
+	}
+
+	for i := range energy {
+		energy[i] += perSpecies[pf.speciesOf[i]]
+	}
+	return virial
+}
+
+// Accelerations는 ForceField 인터페이스 구현입니다. Energy, Virial도 새 배열로 갱신합니다.
+func (pf *PairForce) Accelerations(sim *Simulator) []Vector {
+	acc, energy, virial := pf.Compute(sim)
+	pf.Energy = energy
+	pf.Virial = virial
+	pf.remember(sim)
+	return acc
+}
+
+// AddAccelerations는 AccelerationAdder 인터페이스 구현입니다.
+// Accelerations와 같은 가속도를 acc에 더하고 Energy(배열 재사용), Virial을 갱신합니다.
+func (pf *PairForce) AddAccelerations(sim *Simulator, acc []Vector) {
+	pf.Energy = resize(pf.Energy, sim.N)
+	pf.Virial = pf.compute(sim, acc, pf.Energy)
+	pf.remember(sim)
+}
+
+// key는 현재 파라미터입니다.
+func (pf *PairForce) key(sim *Simulator) energyKey {
+	return energyKey{pf.Potential, pf.RCut, pf.Shift, pf.TailCorrection, pf.Periodic, pf.pairsVersion, sim.RegionSize}
+}
+
+// remember는 Energy를 계산한 위치, 종 Id, 파라미터를 기억합니다.
+func (pf *PairForce) remember(sim *Simulator) {
+	pf.energyPos = resize(pf.energyPos, sim.N)
+	copy(pf.energyPos, sim.Pos)
+	pf.energyIds = resize(pf.energyIds, sim.N)
+	copy(pf.energyIds, sim.Id)
+	pf.energyKey = pf.key(sim)
+}
+
+// PotentialEnergy는 PotentialField 인터페이스 구현입니다.
+// 위치(주기 이동 제외), 종 Id, 파라미터(Potential, RCut, Shift, TailCorrection, Periodic,
+// SetPair, sim.RegionSize)가 마지막 힘 계산 때와 같으면 그때 모은 Energy를 더하고,
+// 아니면 Energy, Virial을 건드리지 않고 에너지만 다시 계산합니다.
+func (pf *PairForce) PotentialEnergy(sim *Simulator) float64 {
+	energy := pf.Energy
+	if !pf.energyCurrent(sim) {
+		pf.scratch = resize(pf.scratch, sim.N)
+		pf.compute(sim, nil, pf.scratch)
+		energy = pf.scratch
+	}
+	U := 0.0
+	for _, e := range energy {
+		U += e
+	}
+	return U
+}
+
+// energyCurrent는 Energy가 현재 위치, 종 Id, 파라미터의 값인지 판단합니다.
+func (pf *PairForce) energyCurrent(sim *Simulator) bool {
+	if len(pf.Energy) != sim.N || len(pf.energyPos) != sim.N || !pf.energyKey.equal(pf.key(sim)) ||
+		!slices.Equal(pf.energyIds, sim.Id[:sim.N]) {
+		return false
+	}
+	for i, p := range sim.Pos {
+		if p != pf.energyPos[i] && !wrapped(pf.energyPos[i], p, sim.wrapLength) {
+			return false
+		}
+	}
+	return true
+}
+
+// equal은 두 파라미터가 같은지 비교합니다. 비교할 수 없는 퍼텐셜 타입 (슬라이스 필드 등) 은 다르다고 봅니다.
+func (k energyKey) equal(o energyKey) bool {
+	if k.potential != nil && !reflect.TypeOf(k.potential).Comparable() {
+		return false
+	}
+	return k == o
+}
+
+// Pressure는 비리얼 정리로 압력을 계산합니다: P = (2K/3 + W/3) / V
+// W는 마지막 Accelerations 호출의 Virial을 사용합니다.
+func (pf *PairForce) Pressure(sim *Simulator) float64 {
+	V := sim.RegionSize * sim.RegionSize * sim.RegionSize
+	return (2*sim.KineticEnergy()/3 + pf.Virial/3) / V
+}
@@ -0,0 +1,276 @@
+package atom3D
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestPairPotentialForce(t *testing.T) {
+	potentials := []PairPotential{
+		LennardJones{Epsilon: 1, Sigma: 1},
+		WCA{Epsilon: 1, Sigma: 1},
+		Morse{D: 2, A: 1.5, R0: 1.1},
+		Yukawa{A: 3, Kappa: 0.7},
+		SoftSphere{Epsilon: 1, Sigma: 1, N: 12},
+	}
+	// F = -dU/dr (중앙 차분)
+	h := 1e-6
+	for _, pot := range potentials {
+		for _, r := range []float64{0.95, 1.05, 1.3, 2.0} {
+			want := -(pot.Energy(r+h) - pot.Energy(r-h)) / (2 * h)
+			if got := pot.Force(r); math.Abs(got-want) > 1e-5*(1+math.Abs(want)) {
+				t.Errorf("%T: F(%v) = %v, -dU/dr = %v", pot, r, got, want)
+			}
+		}
+	}
+}
+
+func TestTailIntegrals(t *testing.T) {
+	rc := 2.5
+	for _, pot := range []PairPotential{LennardJones{1, 1}, Yukawa{2, 1.3}, SoftSphere{1, 1, 9}} {
+		tc := pot.(TailCorrector)
+		energy, virial := tc.TailIntegrals(rc)
+		// 사다리꼴 적분
+		e, w := 0., 0.
+		dr := 1e-3
+		for r := rc; r < 60; r += dr {
+			e += 4 * math.Pi * r * r * pot.Energy(r+dr/2) * dr
+			w += 4 * math.Pi * r * r * (r + dr/2) * pot.Force(r+dr/2) * dr
+		}
+		if math.Abs(e-energy) > 1e-3*math.Abs(energy) || math.Abs(w-virial) > 1e-3*math.Abs(virial) {
+			t.Errorf("%T: tail (%v, %v), numeric (%v, %v)", pot, energy, virial, e, w)
+		}
+	}
+}
+
+// 셀 목록으로 모은 힘, 에너지, 비리얼은 최소 이미지 직접합과 같아야 합니다.
+// 한 변의 셀이 1, 2개인 작은 주기 격자에서도 각 쌍은 한 번만 세어야 합니다.
+func TestPairForceBruteForce(t *testing.T) {
+	L := 8.
+	N := 200
+	rng := rand.New(rand.NewSource(1))
+	pos := make([]Vector, N)
+	vel := make([]Vector, N)
+	id := make([]int, N)
+	for i := range pos {
+		pos[i] = Vector{(rng.Float64() - 0.5) * L, (rng.Float64() - 0.5) * L, (rng.Float64() - 0.5) * L}
+		id[i] = i % 2
+	}
+	sim := NewSimulator(0.001, id, pos, vel, Vector{})
+	sim.RegionSize = L
+	sim.Mass[1] = 2.
+
+	lj := LennardJones{Epsilon: 1, Sigma: 0.5}
+	pf := NewPairForce(lj, 2.5)
+	pf.Shift = ForceShift
+	pf.Periodic = true
+	pf.SetPair(0, 1, Morse{D: 1, A: 2, R0: 0.6})
+
+	for _, gridSize := range []float64{2.5, 6, 10} { // n = 4, 2, 1
+		sim.GridSize = gridSize
+		acc, energy, virial := pf.Compute(sim)
+
+		wantVirial := 0.
+		for i := 0; i < N; i++ {
+			var f Vector
+			e := 0.
+			for j := 0; j < N; j++ {
+				if j == i {
+					continue
+				}
+				pot := pf.potential(id[i], id[j])
+				d := sim.PeriodicDisplacement(i, j)
+				r := d.Abs()
+				u, fr := pf.eval(pot, r, 2.5)
+				f = f.Sub(d.Mul(fr / r))
+				e += 0.5 * u
+				wantVirial += 0.5 * r * fr
+			}
+			f = f.Div(sim.Mass[i])
+			if acc[i].Sub(f).Abs() > 1e-9*(1+f.Abs()) {
+				t.Fatalf("GridSize %v: acc[%d] = %v, want %v", gridSize, i, acc[i], f)
+			}
+			if math.Abs(energy[i]-e) > 1e-9*(1+math.Abs(e)) {
+				t.Fatalf("GridSize %v: energy[%d] = %v, want %v", gridSize, i, energy[i], e)
+			}
+		}
+		if math.Abs(virial-wantVirial) > 1e-9*(1+math.Abs(wantVirial)) {
+			t.Errorf("GridSize %v: virial = %v, want %v", gridSize, virial, wantVirial)
+		}
+	}
+}
+
+// 멀리 떨어진 종 Id(0, 100000)도 표는 있는 종 수(2×2)로만 만들고, 힘은 쌍별 직접 합과 같아야 합니다.
+func TestPairForceSparseSpecies(t *testing.T) {
+	L := 6.
+	N := 60
+	rng := rand.New(rand.NewSource(2))
+	pos := make([]Vector, N)
+	id := make([]int, N)
+	for i := range pos {
+		pos[i] = Vector{(rng.Float64() - 0.5) * L, (rng.Float64() - 0.5) * L, (rng.Float64() - 0.5) * L}
+		id[i] = []int{0, 100000}[i%2]
+	}
+	sim := NewSimulator(0.001, id, pos, make([]Vector, N), Vector{})
+	sim.RegionSize = L
+	sim.GridSize = 2
+
+	pf := NewPairForce(LennardJones{Epsilon: 1, Sigma: 0.5}, 2)
+	pf.Periodic = true
+	pf.SetPair(0, 100000, Yukawa{A: 1, Kappa: 2})
+	acc, _, _ := pf.Compute(sim)
+	if len(pf.table) != 4 {
+		t.Fatalf("table has %d entries, want 4", len(pf.table))
+	}
+
+	for i := 0; i < N; i++ {
+		var f Vector
+		for j := 0; j < N; j++ {
+			if j == i {
+				continue
+			}
+			d := sim.PeriodicDisplacement(i, j)
+			r := d.Abs()
+			_, fr := pf.eval(pf.potential(id[i], id[j]), r, 2)
+			f = f.Sub(d.Mul(fr / r))
+		}
+		if acc[i].Sub(f).Abs() > 1e-9*(1+f.Abs()) {
+			t.Fatalf("acc[%d] = %v, want %v", i, acc[i], f)
+		}
+	}
+}
+
+// 겹친 두 파티클(r = 0)은 서로 기여하지 않고, 나머지 쌍의 힘·에너지·비리얼은 유한해야 합니다.
+func TestPairForceOverlap(t *testing.T) {
+	pos := []Vector{{0, 0, 0}, {0, 0, 0}, {1, 0, 0}}
+	sim := NewSimulator(0.001, []int{0, 0, 0}, pos, make([]Vector, 3), Vector{})
+	sim.RegionSize = 8
+	sim.GridSize = 2.5
+
+	lj := LennardJones{Epsilon: 1, Sigma: 0.8}
+	pf := NewPairForce(lj, 2.5)
+	pf.Periodic = true
+	acc, energy, virial := pf.Compute(sim)
+
+	f, u := lj.Force(1), lj.Energy(1)
+	want := []Vector{{-f, 0, 0}, {-f, 0, 0}, {2 * f, 0, 0}}
+	wantEnergy := []float64{0.5 * u, 0.5 * u, u}
+	for i := range want {
+		if !(acc[i].Sub(want[i]).Abs() <= 1e-12*math.Abs(f)) || !(math.Abs(energy[i]-wantEnergy[i]) <= 1e-12*math.Abs(u)) { // NaN도 실패
+			t.Errorf("particle %d: acc %v, E %v; want %v, %v", i, acc[i], energy[i], want[i], wantEnergy[i])
+		}
+	}
+	if !(math.Abs(virial-2*f) <= 1e-12*math.Abs(f)) {
+		t.Errorf("virial = %v, want %v", virial, 2*f)
+	}
+}
+
+// AddAccelerations는 Accelerations와 같은 가속도·에너지·비리얼을 작업 버퍼로 내야 하며,
+// PotentialEnergy는 같은 위치에서는 다시 계산하지 않고 (이웃 격자를 건드리지 않음) 모은 에너지를 써야 합니다.
+func TestPairForceAddAccelerations(t *testing.T) {
+	L := 8.
+	sim := ewaldSimulator(perturbedLattice(6, L, 0.5, 11), L)
+	for i := range sim.Id {
+		sim.Id[i] = []int{-1, 3, 5}[i%3] // 연속하지 않는 종 Id
+	}
+	sim.GridSize = 2.5
+	newForce := func() *PairForce {
+		pf := NewPairForce(LennardJones{Epsilon: 1, Sigma: 1}, 2.5)
+		pf.SetPair(3, 5, Yukawa{A: 2, Kappa: 1.3})
+		pf.SetPair(-1, -1, Morse{D: 2, A: 1.5, R0: 1.1})
+		pf.Shift = EnergyShift
+		pf.TailCorrection = true
+		pf.Periodic = true
+		return pf
+	}
+	ref, pf := newForce(), newForce()
+	want := ref.Accelerations(sim)
+	acc := make([]Vector, sim.N)
+	for i := range acc {
+		acc[i] = Vector{1, 2, 3}
+	}
+	pf.AddAccelerations(sim, acc)
+	for i := range want {
+		if d := acc[i].Sub(Vector{1, 2, 3}).Sub(want[i]).Abs(); d > 1e-12*(1+want[i].Abs()) || pf.Energy[i] != ref.Energy[i] {
+			t.Fatalf("particle %d: AddAccelerations %v, E %v; Accelerations %v, E %v", i, acc[i], pf.Energy[i], want[i], ref.Energy[i])
+		}
+	}
+	if math.Abs(pf.Virial-ref.Virial) > 1e-12*math.Abs(ref.Virial) {
+		t.Errorf("virial %v, want %v", pf.Virial, ref.Virial)
+	}
+
+	// 같은 위치: 모은 에너지를 그대로 사용
+	_, energy, _ := ref.Compute(sim)
+	wantU := 0.
+	for _, e := range energy {
+		wantU += e
+	}
+	sim.CellStart, sim.ParticleIndex = nil, nil
+	if U := pf.PotentialEnergy(sim); math.Abs(U-wantU) > 1e-12*math.Abs(wantU) || sim.ParticleIndex != nil {
+		t.Errorf("PotentialEnergy = %v (rebuilt grid: %v), want %v without a new pass", U, sim.ParticleIndex != nil, wantU)
+	}
+
+	// 움직인 뒤: 에너지만 다시 계산하고 Energy, Virial은 그대로
+	for i := range sim.Pos {
+		sim.Pos[i] = sim.Pos[i].Add(Vector{0.01, -0.02, 0.015})
+	}
+	sim.PeriodicBoundary(L)
+	energyBefore, virialBefore := append([]float64(nil), pf.Energy...), pf.Virial
+	_, energy, _ = ref.Compute(sim)
+	wantU = 0.
+	for _, e := range energy {
+		wantU += e
+	}
+	if U := pf.PotentialEnergy(sim); math.Abs(U-wantU) > 1e-12*math.Abs(wantU) {
+		t.Errorf("PotentialEnergy after a move = %v, want %v", U, wantU)
+	}
+	if pf.Virial != virialBefore || !slices.Equal(pf.Energy, energyBefore) {
+		t.Error("PotentialEnergy changed Energy or Virial")
+	}
+
+	// 정상 상태의 AddAccelerations는 (워커 하나에서) 할당이 없어야 합니다.
+	sim.Parallel = Parallel{Workers: 1}
+	if allocs := testing.AllocsPerRun(3, func() { pf.AddAccelerations(sim, acc) }); allocs != 0 {
+		t.Errorf("%.0f allocs per AddAccelerations, want 0", allocs)
+	}
+}
+
+// 마지막 힘 계산 뒤 파라미터나 종 Id를 바꾸면 PotentialEnergy는 모은 Energy 대신 새 값을 내야 합니다.
+func TestPairForcePotentialEnergyStale(t *testing.T) {
+	L := 8.
+	changes := map[string]func(sim *Simulator, pf *PairForce){
+		"SetPair":        func(sim *Simulator, pf *PairForce) { pf.SetPair(0, 1, Morse{D: 1, A: 2, R0: 1}) },
+		"Potential":      func(sim *Simulator, pf *PairForce) { pf.Potential = LennardJones{Epsilon: 2, Sigma: 1} },
+		"RCut":           func(sim *Simulator, pf *PairForce) { pf.RCut = 2 },
+		"Shift":          func(sim *Simulator, pf *PairForce) { pf.Shift = ForceShift },
+		"TailCorrection": func(sim *Simulator, pf *PairForce) { pf.TailCorrection = false },
+		"RegionSize":     func(sim *Simulator, pf *PairForce) { sim.RegionSize = 9 },
+		"Id":             func(sim *Simulator, pf *PairForce) { sim.Id[0] = 1 - sim.Id[0] },
+	}
+	for name, change := range changes {
+		sim := ewaldSimulator(perturbedLattice(6, L, 0.5, 11), L)
+		for i := range sim.Id {
+			sim.Id[i] = i % 2
+		}
+		sim.GridSize = 2.5
+		pf := NewPairForce(LennardJones{Epsilon: 1, Sigma: 1}, 2.5)
+		pf.SetPair(1, 1, Yukawa{A: 2, Kappa: 1.3})
+		pf.Shift = EnergyShift
+		pf.TailCorrection = true
+		pf.Periodic = true
+		pf.AddAccelerations(sim, make([]Vector, sim.N))
+
+		change(sim, pf)
+		U := pf.PotentialEnergy(sim)
+		_, energy, _ := pf.Compute(sim)
+		wantU := 0.
+		for _, e := range energy {
+			wantU += e
+		}
+		if math.Abs(U-wantU) > 1e-12*math.Abs(wantU) {
+			t.Errorf("%s: PotentialEnergy = %v, want %v", name, U, wantU)
+		}
+	}
+}
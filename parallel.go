@@ -0,0 +1,93 @@
+package atom3D
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ── Parallel ─────────────────────────────────────────────────────────────────
+
+// Parallel은 시뮬레이터의 병렬 실행 정책입니다. 힘 계산, 적분기, 경계 처리, 이웃 격자 생성 등
+// 파티클 루프가 모두 sim.Parallel.For를 거치므로 기계마다 워커 수와 조각 크기를 한 곳에서 조정합니다.
+//
+//	sim.Parallel = atom3D.Parallel{Workers: 8, ChunkSize: 256}
+//
+// 워커는 공유 카운터에서 ChunkSize 크기의 연속 구간을 차례로 가져가므로,
+// 파티클마다 비용이 다른 루프(트리 탐색, 밀집 셀의 PP)도 부하가 고르게 나뉩니다.
+type Parallel struct {
+	Workers   int // 워커 goroutine 수 (0 이하이면 runtime.NumCPU())
+	ChunkSize int // 워커가 한 번에 가져가는 인덱스 수 (0 이하이면 n/(8·Workers), 최소 1)
+}
+
+// workers는 실제 워커 수를 반환합니다.
+func (p Parallel) workers() int {
+	if p.Workers <= 0 {
+		return runtime.NumCPU()
+	}
+	return p.Workers
+}
+
+// chunk는 길이 n인 루프의 조각 크기를 반환합니다.
+func (p Parallel) chunk(n int) int {
+	if p.ChunkSize > 0 {
+		return p.ChunkSize
+	}
+	return max(1, n/(8*p.workers()))
+}
+
+// For는 [0, n)을 조각으로 나눠 body(w, lo, hi)를 병렬로 실행하고 모두 끝날 때까지 기다립니다.
+// w는 0 <= w < workers() 인 워커 번호로, 워커별 버퍼나 누적값을 잠금 없이 쓰는 데 사용합니다.
+// 조각이 하나뿐이거나 워커가 하나이면 호출한 goroutine에서 바로 실행합니다.
+//
+// body 클로저는 호출마다 힙에 할당되므로, 매 스텝 도는 패키지 안의 루프는 run을 씁니다.
+func (p Parallel) For(n int, body func(w, lo, hi int)) {
+	p.run(n, loopFunc(body))
+}
+
+// rangeLoop은 Parallel.run에 넘기는 루프 몸체입니다. 솔버가 가진 작업 구조체의 포인터로
+// 구현하면 인자를 필드에 담아 넘기므로 워커가 하나일 때 루프 호출에 할당이 없습니다.
+// (워커가 여럿이면 goroutine을 띄우는 몫의 할당은 남습니다.)
+type rangeLoop interface {
+	run(w, lo, hi int)
+}
+
+// loopFunc는 For의 클로저를 rangeLoop으로 씁니다.
+type loopFunc func(w, lo, hi int)
+
+func (f loopFunc) run(w, lo, hi int) { f(w, lo, hi) }
+
+// run은 For와 같은 방식으로 [0, n)을 나눠 body.run(w, lo, hi)를 실행합니다.
+func (p Parallel) run(n int, body rangeLoop) {
+	if n <= 0 {
+		return
+	}
+	chunk := p.chunk(n)
+	workers := min(p.workers(), (n+chunk-1)/chunk)
+	if workers <= 1 {
+		body.run(0, 0, n)
+		return
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for {
+				lo := int(next.Add(int64(chunk))) - chunk
+				if lo >= n {
+					return
+				}
+				body.run(w, lo, min(lo+chunk, n))
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// tasks는 조각 크기 1인 정책을 반환합니다. 작업 하나가 큰 루프(타일 쌍, 부분 트리, 패치)에 씁니다.
+func (p Parallel) tasks() Parallel {
+	return Parallel{Workers: p.Workers, ChunkSize: 1}
+}
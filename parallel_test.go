@@ -0,0 +1,71 @@
+package atom3D
+
+import (
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+// For는 모든 인덱스를 정확히 한 번 방문하고, 워커 번호는 workers() 범위 안이어야 합니다.
+func TestParallelFor(t *testing.T) {
+	for _, par := range []Parallel{{}, {Workers: 1}, {Workers: 3, ChunkSize: 1}, {Workers: 8, ChunkSize: 7}, {Workers: 64}} {
+		for _, n := range []int{0, 1, 5, 1000} {
+			visits := make([]int32, n)
+			var bad atomic.Bool
+			par.For(n, func(w, lo, hi int) {
+				if w < 0 || w >= par.workers() || lo >= hi {
+					bad.Store(true)
+				}
+				for i := lo; i < hi; i++ {
+					atomic.AddInt32(&visits[i], 1)
+				}
+			})
+			if bad.Load() {
+				t.Errorf("%+v, n = %d: invalid worker or empty chunk", par, n)
+			}
+			for i, v := range visits {
+				if v != 1 {
+					t.Errorf("%+v, n = %d: index %d visited %d times", par, n, i, v)
+					break
+				}
+			}
+		}
+	}
+}
+
+// 병렬 MakeGrid와 P3M 할당·힘은 워커 수와 조각 크기에 무관하게 같은 결과를 내야 합니다.
+func TestParallelDeterminism(t *testing.T) {
+	L := 10.
+	sim := ewaldSimulator(randomPositions(3000, L, 17), L)
+	p3m := NewP3M(32, L, 1.)
+	sim.GridSize = p3m.RCut
+
+	var cells []int
+	var rho []float64
+	var acc []Vector
+	for k, par := range []Parallel{{Workers: 1}, {Workers: 4, ChunkSize: 1}, {Workers: 7, ChunkSize: 100}} {
+		sim.Parallel = par
+		acc1 := p3m.Accelerations(sim)
+		p3m.parallel = par
+		rho1 := p3m.AssignDensity(sim.Pos, sim.Mass)
+		if k == 0 {
+			// MakeGrid는 셀 목록 배열을 재사용하므로 복사해 둡니다.
+			cells, rho, acc = slices.Clone(sim.ParticleIndex), rho1, acc1
+			for c := 0; c+1 < len(sim.CellStart); c++ {
+				if cell := sim.Cell(c); !slices.IsSorted(cell) {
+					t.Fatalf("cell %d not sorted: %v", c, cell)
+				}
+			}
+			continue
+		}
+		if !slices.Equal(cells, sim.ParticleIndex) {
+			t.Fatalf("%+v: cell list differs from serial MakeGrid", par)
+		}
+		if !slices.Equal(rho, rho1) {
+			t.Errorf("%+v: density differs from serial assignment", par)
+		}
+		if err := CompareForces(acc1, acc); err.Max > 1e-12 {
+			t.Errorf("%+v: P3M accelerations differ from serial: max %.3e", par, err.Max)
+		}
+	}
+}
@@ -60,6 +60,23 @@ func (render Render) DrawAtom(dc *gg.Context, pos Vector, radius float64, rgba [
 	dc.Fill()
 }
 
+// DrawParticle은 파티클 i를 종 테이블의 기본 반경과 색상으로 그립니다.
+func (render Render) DrawParticle(dc *gg.Context, sim *Simulator, i int) {
+	species := sim.SpeciesOf(i)
+	rgba := species.Color
+	if len(rgba) != 4 {
+		rgba = DefaultSpecies.Color
+	}
+	render.DrawAtom(dc, sim.Pos[i], species.Radius, rgba)
+}
+
+// DrawParticles는 모든 파티클을 깊이 순으로 정렬해 종 기본값으로 그립니다.
+func (render Render) DrawParticles(dc *gg.Context, sim *Simulator) {
+	for _, i := range render.GetSortedIndices(sim.Pos) {
+		render.DrawParticle(dc, sim, i)
+	}
+}
+
 func (render Render) DrawText(dc *gg.Context, pos Vector, text string, font_size float64, font string, rgba []float64) {
 	render_pos := RenderSO3(render.Angle).DotV(pos)
 	ratio := render.FocusFactor * render.Depth / (render_pos.Y + render.Depth)
@@ -0,0 +1,185 @@
+package atom3D
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/hdf5"
+)
+
+// Species는 파티클 종(種)의 물리량과 기본 표시 속성입니다.
+// Simulator.Id[i]는 Simulator.Species 테이블의 인덱스로 해석됩니다.
+type Species struct {
+	Name   string
+	Mass   float64
+	Radius float64   // 렌더링 및 접촉 반경
+	Charge float64   // 부호 있는 전하
+	Color  []float64 // 기본 RGBA 색상
+}
+
+// DefaultSpecies는 Id가 테이블 범위를 벗어날 때 사용되는 기본 종입니다.
+var DefaultSpecies = Species{
+	Name:   "default",
+	Mass:   1.0,
+	Radius: 1.0,
+	Charge: 0.0,
+	Color:  []float64{1, 1, 1, 1},
+}
+
+// AddSpecies는 종을 테이블에 등록하고 그 Id를 반환합니다.
+func (simulator *Simulator) AddSpecies(species Species) int {
+	simulator.Species = append(simulator.Species, species)
+	return len(simulator.Species) - 1
+}
+
+// FindSpecies는 이름으로 종 Id를 찾습니다. 없으면 -1을 반환합니다.
+func (simulator *Simulator) FindSpecies(name string) int {
+	for id, species := range simulator.Species {
+		if species.Name == name {
+			return id
+		}
+	}
+	return -1
+}
+
+// SpeciesOf는 파티클 i의 종을 반환합니다.
+// Id가 테이블 범위를 벗어나면 DefaultSpecies를 반환합니다.
+func (simulator *Simulator) SpeciesOf(i int) Species {
+	id := simulator.Id[i]
+	if id < 0 || id >= len(simulator.Species) {
+		return DefaultSpecies
+	}
+	return simulator.Species[id]
+}
+
+// ApplySpecies는 종 테이블의 질량과 전하를 각 파티클의 Mass, Charge에 복사합니다.
+// 파티클 Id를 설정한 뒤 한 번 호출하면 됩니다.
+func (simulator *Simulator) ApplySpecies() {
+	for i := 0; i < simulator.N; i++ {
+		species := simulator.SpeciesOf(i)
+		simulator.Mass[i] = species.Mass
+		simulator.Charge[i] = species.Charge
+	}
+}
+
+// ── HDF5 종 테이블 ───────────────────────────────────────────────────────────
+
+// saveSpecies는 종 테이블을 "/Species" 그룹에 저장합니다.
+//
+//	Names  : 줄마다 Go 따옴표 문자열 하나 (Attribute, encodeSpeciesNames)
+//	Mass, Radius, Charge : [S] 데이터셋
+//	Color  : [S, 4] 데이터셋
+func saveSpecies(rootGroup *hdf5.Group, table []Species) {
+	if len(table) == 0 {
+		return
+	}
+	group, err := rootGroup.CreateGroup("Species")
+	if err != nil {
+		log.Fatalf("Error creating species group: %s", err)
+	}
+	defer group.Close()
+
+	S := len(table)
+	names := make([]string, S)
+	mass := make([]float64, S)
+	radius := make([]float64, S)
+	charge := make([]float64, S)
+	color := make([]float64, 4*S)
+	for s, species := range table {
+		names[s] = species.Name
+		mass[s] = species.Mass
+		radius[s] = species.Radius
+		charge[s] = species.Charge
+		rgba := species.Color
+		if len(rgba) != 4 {
+			rgba = DefaultSpecies.Color
+		}
+		copy(color[4*s:4*s+4], rgba)
+	}
+
+	CreateAttributeString(group, "Names", encodeSpeciesNames(names))
+	CreateDatasetFloat(group, "Mass", mass, []uint{uint(S)})
+	CreateDatasetFloat(group, "Radius", radius, []uint{uint(S)})
+	CreateDatasetFloat(group, "Charge", charge, []uint{uint(S)})
+	CreateDatasetFloat(group, "Color", color, []uint{uint(S), 4})
+}
+
+// encodeSpeciesNames는 이름마다 strconv.Quote로 이스케이프해 줄바꿈으로 잇습니다.
+// 따옴표 문자열에는 줄바꿈이 그대로 들어가지 않으므로 어떤 이름도 구분자와 섞이지 않습니다.
+func encodeSpeciesNames(names []string) string {
+	quoted := make([]string, len(names))
+	for s, name := range names {
+		quoted[s] = strconv.Quote(name)
+	}
+	return strings.Join(quoted, "\n")
+}
+
+// decodeSpeciesNames는 encodeSpeciesNames의 역입니다.
+// 따옴표가 없는 줄은 이스케이프 이전 형식(이름을 그대로 줄바꿈으로 이은 것)으로 읽습니다.
+func decodeSpeciesNames(encoded string) []string {
+	names := strings.Split(encoded, "\n")
+	for s, line := range names {
+		if name, err := strconv.Unquote(line); err == nil && strings.HasPrefix(line, `"`) {
+			names[s] = name
+		}
+	}
+	return names
+}
+
+// decodeSpecies는 "/Species" 그룹에서 읽은 배열로 종 테이블을 만듭니다.
+// 배열 길이가 종 수 S와 맞지 않으면 (Names S줄, Mass·Radius·Charge S개, Color 4S개) 오류를 반환합니다.
+func decodeSpecies(names []string, mass, radius, charge, color []float64) ([]Species, error) {
+	S := len(mass)
+	if len(names) != S || len(radius) != S || len(charge) != S || len(color) != 4*S {
+		return nil, fmt.Errorf("species table size mismatch: %d names, %d masses, %d radii, %d charges, %d color values",
+			len(names), len(mass), len(radius), len(charge), len(color))
+	}
+	table := make([]Species, S)
+	for s := range table {
+		table[s] = Species{
+			Name:   names[s],
+			Mass:   mass[s],
+			Radius: radius[s],
+			Charge: charge[s],
+			Color:  append([]float64{}, color[4*s:4*s+4]...),
+		}
+	}
+	return table, nil
+}
+
+// readSpecies는 "/Species" 그룹에서 종 테이블을 읽습니다. 그룹이 없으면 nil을 반환합니다.
+func readSpecies(rootGroup *hdf5.Group) ([]Species, error) {
+	if !rootGroup.LinkExists("Species") {
+		return nil, nil
+	}
+	group, err := rootGroup.OpenGroup("Species")
+	if err != nil {
+		return nil, fmt.Errorf("opening species group: %w", err)
+	}
+	defer group.Close()
+
+	return decodeSpecies(
+		decodeSpeciesNames(ReadAttributeString(group, "Names")),
+		ReadDatasetFloat(group, "Mass"),
+		ReadDatasetFloat(group, "Radius"),
+		ReadDatasetFloat(group, "Charge"),
+		ReadDatasetFloat(group, "Color"),
+	)
+}
+
+// ReadSpecies는 스냅샷 파일의 종 테이블을 읽습니다.
+// 상태 전체가 필요하면 파일을 한 번만 여는 ReadSnapshot(Snapshot.Species)을 사용하세요.
+func ReadSpecies(filename string) ([]Species, error) {
+	file, err := hdf5.OpenFile(filename, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rootGroup, _ := file.OpenGroup("/")
+	defer rootGroup.Close()
+
+	return readSpecies(rootGroup)
+}
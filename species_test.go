@@ -0,0 +1,73 @@
+package atom3D
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// 이름에 줄바꿈이나 따옴표가 있어도 종 이름은 그대로 복원되어야 합니다.
+func TestSpeciesNames(t *testing.T) {
+	names := []string{"Na", "Cl-\nion", `"quoted"`, "", "탄소 \\n", "tab\there"}
+	if got := decodeSpeciesNames(encodeSpeciesNames(names)); !slices.Equal(got, names) {
+		t.Errorf("round trip of %q gives %q", names, got)
+	}
+
+	// 이스케이프 이전 형식 (이름을 그대로 줄바꿈으로 이은 것)
+	if got := decodeSpeciesNames("Na\nCl"); !slices.Equal(got, []string{"Na", "Cl"}) {
+		t.Errorf("legacy names decode to %q", got)
+	}
+}
+
+// 배열 길이가 맞지 않는 종 테이블은 패닉 없이 오류를 반환해야 합니다.
+func TestDecodeSpeciesMismatch(t *testing.T) {
+	names := []string{"a", "b"}
+	two := []float64{1, 2}
+	color := make([]float64, 8)
+	if table, err := decodeSpecies(names, two, two, two, color); err != nil || len(table) != 2 {
+		t.Fatalf("valid table: %v, %v", table, err)
+	}
+	for name, c := range map[string]struct {
+		names                 []string
+		mass, radius, charges []float64
+		color                 []float64
+	}{
+		"names":  {names[:1], two, two, two, color},
+		"mass":   {names, two[:1], two, two, color},
+		"radius": {names, two, two[:1], two, color},
+		"charge": {names, two, two, []float64{1, 2, 3}, color},
+		"color":  {names, two, two, two, color[:6]},
+	} {
+		if _, err := decodeSpecies(c.names, c.mass, c.radius, c.charges, c.color); err == nil {
+			t.Errorf("%s length mismatch: no error", name)
+		}
+	}
+}
+
+// Save → Load 왕복은 종 테이블(줄바꿈이 든 이름 포함)과 파티클 상태를 그대로 복원해야 합니다.
+func TestSpeciesSaveLoad(t *testing.T) {
+	sim := NewSimulator(0.01, []int{0, 1, 1}, []Vector{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}, make([]Vector, 3), Vector{0, 0, -1})
+	sim.AddSpecies(Species{Name: "Na", Mass: 22.99, Radius: 1.0, Charge: +1, Color: []float64{0.6, 0.4, 1, 1}})
+	sim.AddSpecies(Species{Name: "Cl\n\"ion\"", Mass: 35.45, Radius: 1.8, Charge: -1, Color: []float64{0.2, 1, 0.2, 1}})
+	sim.ApplySpecies()
+	sim.Count = 7
+
+	dir := t.TempDir()
+	sim.Save(dir)
+	loaded := NewSimulator(0, nil, nil, nil, Vector{})
+	loaded.Load(fmt.Sprintf("%s/snapshot_%010d.hdf5", dir, sim.Count))
+
+	if len(loaded.Species) != len(sim.Species) {
+		t.Fatalf("loaded %d species, want %d", len(loaded.Species), len(sim.Species))
+	}
+	for s, want := range sim.Species {
+		got := loaded.Species[s]
+		if got.Name != want.Name || got.Mass != want.Mass || got.Radius != want.Radius || got.Charge != want.Charge || !slices.Equal(got.Color, want.Color) {
+			t.Errorf("species %d = %+v, want %+v", s, got, want)
+		}
+	}
+	if !slices.Equal(loaded.Id, sim.Id) || !slices.Equal(loaded.Mass, sim.Mass) || !slices.Equal(loaded.Charge, sim.Charge) || !slices.Equal(loaded.Pos, sim.Pos) {
+		t.Errorf("loaded particles Id %v, Mass %v, Charge %v, Pos %v; want %v, %v, %v, %v",
+			loaded.Id, loaded.Mass, loaded.Charge, loaded.Pos, sim.Id, sim.Mass, sim.Charge, sim.Pos)
+	}
+}
@@ -0,0 +1,269 @@
+package atom3D
+
+import (
+	"bufio"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/hdf5"
+)
+
+// TabulatedTolerance는 ReadTabulatedPotential이 허용하는 힘·에너지 테이블 불일치의 상한입니다.
+// (TabulatedPotential.Consistency 참고)
+var TabulatedTolerance = 1e-2
+
+// ── 3차 스플라인 ─────────────────────────────────────────────────────────────
+
+// cubicSpline은 양 끝 1계 도함수를 지정한 (clamped) 3차 스플라인 보간기입니다.
+type cubicSpline struct {
+	x, y []float64
+	m    []float64 // 각 노드의 2계 도함수
+}
+
+// newCubicSpline은 x[0], x[n-1]에서의 기울기가 d0, dn인 스플라인을 만듭니다.
+func newCubicSpline(x, y []float64, d0, dn float64) cubicSpline {
+	n := len(x)
+	// 삼중대각 방정식 a·m[i-1] + b·m[i] + c·m[i+1] = rhs (Thomas 알고리즘)
+	a := make([]float64, n)
+	b := make([]float64, n)
+	c := make([]float64, n)
+	rhs := make([]float64, n)
+	h0 := x[1] - x[0]
+	b[0], c[0] = h0/3, h0/6
+	rhs[0] = (y[1]-y[0])/h0 - d0
+	for i := 1; i < n-1; i++ {
+		hl := x[i] - x[i-1]
+		hr := x[i+1] - x[i]
+		a[i], b[i], c[i] = hl/6, (hl+hr)/3, hr/6
+		rhs[i] = (y[i+1]-y[i])/hr - (y[i]-y[i-1])/hl
+	}
+	hn := x[n-1] - x[n-2]
+	a[n-1], b[n-1] = hn/6, hn/3
+	rhs[n-1] = dn - (y[n-1]-y[n-2])/hn
+
+	for i := 1; i < n; i++ {
+		w := a[i] / b[i-1]
+		b[i] -= w * c[i-1]
+		rhs[i] -= w * rhs[i-1]
+	}
+	m := make([]float64, n)
+	m[n-1] = rhs[n-1] / b[n-1]
+	for i := n - 2; i >= 0; i-- {
+		m[i] = (rhs[i] - c[i]*m[i+1]) / b[i]
+	}
+	return cubicSpline{x: x, y: y, m: m}
+}
+
+// endSlopes는 3점 한쪽 차분으로 양 끝 기울기를 추정합니다.
+func endSlopes(x, y []float64) (float64, float64) {
+	n := len(x)
+	d0 := (y[1] - y[0]) / (x[1] - x[0])
+	dn := (y[n-1] - y[n-2]) / (x[n-1] - x[n-2])
+	if n > 2 {
+		d1 := (y[2] - y[1]) / (x[2] - x[1])
+		dm := (y[n-2] - y[n-3]) / (x[n-2] - x[n-3])
+		d0 = d0 + (d0-d1)*(x[1]-x[0])/(x[2]-x[0])
+		dn = dn + (dn-dm)*(x[n-1]-x[n-2])/(x[n-1]-x[n-3])
+	}
+	return d0, dn
+}
+
+// segment는 x[k] <= t < x[k+1] 인 구간 k를 반환합니다.
+func (s cubicSpline) segment(t float64) int {
+	k := sort.SearchFloat64s(s.x, t) - 1
+	if k < 0 {
+		k = 0
+	}
+	if k > len(s.x)-2 {
+		k = len(s.x) - 2
+	}
+	return k
+}
+
+func (s cubicSpline) eval(t float64) float64 {
+	k := s.segment(t)
+	h := s.x[k+1] - s.x[k]
+	a := (s.x[k+1] - t) / h
+	b := (t - s.x[k]) / h
+	return a*s.y[k] + b*s.y[k+1] + ((a*a*a-a)*s.m[k]+(b*b*b-b)*s.m[k+1])*h*h/6
+}
+
+func (s cubicSpline) deriv(t float64) float64 {
+	k := s.segment(t)
+	h := s.x[k+1] - s.x[k]
+	a := (s.x[k+1] - t) / h
+	b := (t - s.x[k]) / h
+	return (s.y[k+1]-s.y[k])/h + ((1-3*a*a)*s.m[k]+(3*b*b-1)*s.m[k+1])*h/6
+}
+
+// ── TabulatedPotential ───────────────────────────────────────────────────────
+
+// TabulatedPotential은 (r, U, F) 테이블을 3차 스플라인으로 보간하는 PairPotential입니다.
+// PairForce에 그대로 넣어 사용합니다.
+//
+//	r < R[0]     : F = F(R[0]),  U = U(R[0]) + (R[0] - r)·F(R[0])  (선형 외삽)
+//	r >= R[last] : U = F = 0  (Cutoff = R[last])
+type TabulatedPotential struct {
+	R []float64
+	U []float64
+	F []float64
+
+	uSpline cubicSpline
+	fSpline cubicSpline
+}
+
+// NewTabulatedPotential은 테이블로 퍼텐셜을 생성합니다.
+// 길이가 다르거나, 3점 미만이거나, r이 순증가하지 않으면 panic합니다.
+func NewTabulatedPotential(r, u, f []float64) *TabulatedPotential {
+	if len(r) != len(u) || len(r) != len(f) {
+		panic("tabulated potential: r, U, F length mismatch")
+	}
+	if len(r) < 3 {
+		panic("tabulated potential: at least 3 rows required")
+	}
+	for i := 1; i < len(r); i++ {
+		if r[i] <= r[i-1] {
+			panic("tabulated potential: r must be strictly increasing")
+		}
+	}
+	// U 스플라인의 끝 기울기는 dU/dr = -F 로 고정합니다.
+	n := len(r)
+	f0, fn := endSlopes(r, f)
+	return &TabulatedPotential{
+		R:       r,
+		U:       u,
+		F:       f,
+		uSpline: newCubicSpline(r, u, -f[0], -f[n-1]),
+		fSpline: newCubicSpline(r, f, f0, fn),
+	}
+}
+
+func (t *TabulatedPotential) Cutoff() float64 {
+	return t.R[len(t.R)-1]
+}
+
+func (t *TabulatedPotential) Energy(r float64) float64 {
+	if r >= t.Cutoff() {
+		return 0
+	}
+	if r < t.R[0] {
+		return t.U[0] + (t.R[0]-r)*t.F[0]
+	}
+	return t.uSpline.eval(r)
+}
+
+func (t *TabulatedPotential) Force(r float64) float64 {
+	if r >= t.Cutoff() {
+		return 0
+	}
+	if r < t.R[0] {
+		return t.F[0]
+	}
+	return t.fSpline.eval(r)
+}
+
+// Consistency는 힘 테이블과 에너지 테이블의 불일치를 반환합니다.
+//
+//	max_k |F_k + dU/dr(r_k)| / max_k |F_k|
+//
+// dU/dr은 U 스플라인의 도함수입니다. 양 끝 기울기는 -F로 고정되므로 내부 노드만 비교합니다.
+func (t *TabulatedPotential) Consistency() float64 {
+	maxDiff, maxF := 0.0, 0.0
+	for k := range t.R {
+		maxF = math.Max(maxF, math.Abs(t.F[k]))
+		if k == 0 || k == len(t.R)-1 {
+			continue
+		}
+		maxDiff = math.Max(maxDiff, math.Abs(t.F[k]+t.uSpline.deriv(t.R[k])))
+	}
+	if maxF == 0 {
+		return maxDiff
+	}
+	return maxDiff / maxF
+}
+
+// ── 파일 읽기 ────────────────────────────────────────────────────────────────
+
+// ReadTabulatedPotential은 테이블 파일을 읽어 퍼텐셜을 생성합니다.
+//
+//	.h5, .hdf5 : 루트 그룹의 "r", "U", "F" 데이터셋
+//	그 외      : 공백 구분 텍스트, 행마다 "r U F" 또는 "index r U F" ('#' 주석 허용)
+//
+// 힘·에너지 테이블 불일치가 TabulatedTolerance를 넘으면 종료합니다.
+func ReadTabulatedPotential(filename string) *TabulatedPotential {
+	var r, u, f []float64
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".h5", ".hdf5":
+		r, u, f = readTableHDF5(filename)
+	default:
+		r, u, f = readTableText(filename)
+	}
+
+	table := NewTabulatedPotential(r, u, f)
+	if c := table.Consistency(); c > TabulatedTolerance {
+		log.Fatalf("%s: force and energy tables are inconsistent (max |F + dU/dr| / max |F| = %.3e)", filename, c)
+	}
+	return table
+}
+
+func readTableText(filename string) ([]float64, []float64, []float64) {
+	file, err := os.Open(filename)
+	if err != nil {
+		log.Fatalf("파일을 열 수 없습니다: %v", err)
+	}
+	defer file.Close()
+
+	var r, u, f []float64
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = strings.TrimSpace(text[:i])
+		}
+		if text == "" {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 3 && len(fields) != 4 {
+			log.Fatalf("%s:%d: expected 3 or 4 columns, got %d", filename, line, len(fields))
+		}
+		fields = fields[len(fields)-3:]
+		values := make([]float64, 3)
+		for c, field := range fields {
+			values[c], err = strconv.ParseFloat(field, 64)
+			if err != nil {
+				log.Fatalf("%s:%d: %v", filename, line, err)
+			}
+		}
+		r = append(r, values[0])
+		u = append(u, values[1])
+		f = append(f, values[2])
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("%s: %v", filename, err)
+	}
+	return r, u, f
+}
+
+func readTableHDF5(filename string) ([]float64, []float64, []float64) {
+	file, err := hdf5.OpenFile(filename, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		log.Fatalf("파일을 열 수 없습니다: %v", err)
+	}
+	defer file.Close()
+
+	rootGroup, _ := file.OpenGroup("/")
+	defer rootGroup.Close()
+
+	r := ReadDatasetFloat(rootGroup, "r")
+	u := ReadDatasetFloat(rootGroup, "U")
+	f := ReadDatasetFloat(rootGroup, "F")
+	return r, u, f
+}
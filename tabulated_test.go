@@ -0,0 +1,55 @@
+package atom3D
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTabulatedPotential(t *testing.T) {
+	lj := LennardJones{Epsilon: 1, Sigma: 1}
+
+	// "index r U F" 형식 텍스트 테이블 작성
+	filename := filepath.Join(t.TempDir(), "lj.table")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(file, "# Lennard-Jones, eps = sigma = 1")
+	for k := 0; k <= 400; k++ {
+		r := 0.8 + 2.2*float64(k)/400
+		fmt.Fprintf(file, "%d %.17g %.17g %.17g\n", k+1, r, lj.Energy(r), lj.Force(r))
+	}
+	file.Close()
+
+	table := ReadTabulatedPotential(filename)
+	if c := table.Consistency(); c > 1e-4 {
+		t.Errorf("Consistency = %.3e for exact LJ table", c)
+	}
+	if table.Cutoff() != 3.0 {
+		t.Errorf("Cutoff = %v, want 3", table.Cutoff())
+	}
+	for _, r := range []float64{0.9013, 1.1225, 1.5, 2.7777} {
+		if d := math.Abs(table.Energy(r) - lj.Energy(r)); d > 1e-5 {
+			t.Errorf("U(%v) differs by %.3e", r, d)
+		}
+		if d := math.Abs(table.Force(r) - lj.Force(r)); d > 1e-4 {
+			t.Errorf("F(%v) differs by %.3e", r, d)
+		}
+	}
+
+	// 힘 테이블 부호가 뒤집힌 경우 불일치 검출
+	r := make([]float64, 100)
+	u := make([]float64, 100)
+	f := make([]float64, 100)
+	for k := range r {
+		r[k] = 0.9 + 0.02*float64(k)
+		u[k] = lj.Energy(r[k])
+		f[k] = -lj.Force(r[k])
+	}
+	if c := NewTabulatedPotential(r, u, f).Consistency(); c < 1 {
+		t.Errorf("Consistency = %.3e for inconsistent table, want > 1", c)
+	}
+}
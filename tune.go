@@ -0,0 +1,181 @@
+package atom3D
+
+import (
+	"math"
+)
+
+// ── P3M 파라미터 튜닝 ────────────────────────────────────────────────────────
+
+// P3MTuning은 TuneP3M이 고른 P3M 파라미터와 그 오차·비용 추정입니다.
+//
+// 오차는 기준 힘 F_ref = G·m²/d² (d = L/N^{1/3}, 평균 입자 간격)에 대한 RMS 상대 오차이며,
+// 실공간과 파수공간 오차는 독립으로 가정해 Error = √(RealSpaceError² + KSpaceError²) 입니다.
+// 비용은 부동소수점 연산 수 정도의 무차원 추정치로, PM과 PP의 상대 비교에만 의미가 있습니다.
+type P3MTuning struct {
+	L        float64 // 주기 박스 크기
+	Ng       int     // PM 격자 크기
+	Alpha    float64 // Ewald 분리 파라미터
+	RCut     float64 // PP 컷오프 반경
+	GridSize float64 // 이웃 탐색 셀 크기 (Simulator.GridSize에 설정)
+
+	RealSpaceError float64 // PP 컷오프에 의한 추정 상대 오차
+	KSpaceError    float64 // PM 이산화에 의한 추정 상대 오차
+	Error          float64 // 전체 추정 상대 오차
+
+	PMCost float64 // 스텝당 PM 비용 추정 (FFT + 할당/보간)
+	PPCost float64 // 스텝당 PP 비용 추정 (이웃 쌍 평가)
+}
+
+// 튜닝에 쓰는 비용 가중치 (연산 수 단위)
+const (
+	tunePairCost   = 30.0 // PP 쌍 하나 (erfc, exp, 나눗셈 포함)
+	tuneAssignCost = 2.0  // CIC 할당/보간의 노드 하나
+	tuneMinNg      = 8
+	tuneMaxNg      = 512
+)
+
+// pmSchemeFactor는 기본 PM 설정(FD2 기울기, 단순 Ewald Green 함수 + 디콘볼루션)의
+// 오차가 Deserno–Holm 추정(ik 미분 + 최적 영향 함수)보다 큰 정도입니다.
+// EwaldSum과 비교해 측정한 값입니다 (CIC, Ng = 16..128에서 약 2.5배, 여유를 두어 3).
+const pmSchemeFactor = 3.0
+
+// TuneP3M은 N개의 (질량이 비슷한) 파티클이 크기 L인 주기 박스에 고르게 분포할 때
+// RMS 상대 힘 오차가 accuracy 이하가 되는 P3M 파라미터 중 추정 비용이 가장 작은 것을 고릅니다.
+//
+//	실공간 오차 (Kolafa & Perram 1992):   ΔF_R ≈ 2Q/√(N·rc·L³) · exp(-α²rc²)
+//	파수공간 오차 (Deserno & Holm 1998):  ΔF_K ≈ Q/L² · (hα)^p · √(αL·√(2π)/N · Σ_m a_m (hα)^{2m})
+//
+// Q = Σ m² = N·m², h = L/Ng, p = 2 (CIC). 오차 예산은 두 항에 반씩 (accuracy/√2) 나눕니다.
+// 파수공간 추정은 ik 미분과 최적 영향 함수를 가정하므로, 현재 PM 방식에 맞춰
+// pmSchemeFactor를 곱합니다 (EwaldSum, CompareForces로 확인하세요).
+//
+// Ng는 2의 거듭제곱 중에서 고르며, 어떤 Ng로도 RCut <= L/3을 만족할 수 없으면 panic합니다.
+func TuneP3M(N int, L, accuracy float64) P3MTuning {
+	if N <= 0 || L <= 0 || accuracy <= 0 {
+		panic("tune p3m: N, L and accuracy must be positive")
+	}
+	target := accuracy / math.Sqrt2
+
+	best := P3MTuning{}
+	found := false
+	for ng := tuneMinNg; ng <= tuneMaxNg; ng *= 2 {
+		h := L / float64(ng)
+
+		// 파수공간 오차는 α에 대해 단조 증가 → 예산을 채우는 가장 큰 α
+		alpha := bisect(func(a float64) bool { return kSpaceError(N, L, h, a) <= target }, 1e-6/L, 10/h)
+		// 실공간 오차는 rc에 대해 단조 감소 → 예산을 채우는 가장 작은 rc
+		rCut := bisect(func(rc float64) bool { return realSpaceError(N, L, alpha, rc) <= target }, 1e-6*L, L)
+		if rCut > L/3 || realSpaceError(N, L, alpha, rCut) > target {
+			continue // 주기 이웃 탐색(3×3×3 셀)에 필요한 최소 셀 수를 만족하지 못함
+		}
+
+		t := P3MTuning{
+			L:              L,
+			Ng:             ng,
+			Alpha:          alpha,
+			RCut:           rCut,
+			GridSize:       rCut,
+			RealSpaceError: realSpaceError(N, L, alpha, rCut),
+			KSpaceError:    kSpaceError(N, L, h, alpha),
+		}
+		t.Error = math.Hypot(t.RealSpaceError, t.KSpaceError)
+		t.PMCost, t.PPCost = p3mCost(N, L, ng, rCut)
+
+		if !found || t.PMCost+t.PPCost < best.PMCost+best.PPCost {
+			best = t
+			found = true
+		}
+	}
+	if !found {
+		panic("tune p3m: requested accuracy is not reachable with Ng <= 512")
+	}
+	return best
+}
+
+// P3M은 튜닝 결과로 결합 상수 G의 P3M 솔버를 생성합니다.
+// 쿨롱 상호작용이면 반환값의 Coulomb을 true로 설정하세요 (상대 오차 추정은 같습니다).
+func (t P3MTuning) P3M(G float64) *P3M {
+	return &P3M{
+		Ng:    t.Ng,
+		L:     t.L,
+		G:     G,
+		Alpha: t.Alpha,
+		RCut:  t.RCut,
+
+		TreeTheta: 0.5,
+	}
+}
+
+// Apply는 시뮬레이터의 이웃 탐색 셀 크기를 튜닝 결과에 맞춥니다.
+func (t P3MTuning) Apply(sim *Simulator) {
+	sim.GridSize = t.GridSize
+}
+
+// ── 오차·비용 추정 ───────────────────────────────────────────────────────────
+
+// desernoHolmCIC는 p = 2 (CIC) 할당의 Deserno–Holm 계수 a_m 입니다.
+var desernoHolmCIC = []float64{1. / 50., 5. / 294.}
+
+// referenceForce는 평균 간격 d = L/N^{1/3}에서 단위 질량 두 개 사이의 힘 1/d² 입니다.
+func referenceForce(N int, L float64) float64 {
+	d := L / math.Cbrt(float64(N))
+	return 1 / (d * d)
+}
+
+// realSpaceError는 F_ref로 나눈 실공간 절단 RMS 오차입니다 (단위 질량, G = 1).
+func realSpaceError(N int, L, alpha, rCut float64) float64 {
+	Q := float64(N)
+	dF := 2 * Q / math.Sqrt(float64(N)*rCut*L*L*L) * math.Exp(-alpha*alpha*rCut*rCut)
+	return dF / referenceForce(N, L)
+}
+
+// kSpaceError는 F_ref로 나눈 파수공간 RMS 오차입니다 (단위 질량, G = 1, pmSchemeFactor 포함).
+func kSpaceError(N int, L, h, alpha float64) float64 {
+	Q := float64(N)
+	ha := h * alpha
+	sum, pow := 0.0, 1.0
+	for _, a := range desernoHolmCIC {
+		sum += a * pow
+		pow *= ha * ha
+	}
+	p := float64(len(desernoHolmCIC))
+	dF := Q / (L * L) * math.Pow(ha, p) * math.Sqrt(alpha*L*math.Sqrt(2*math.Pi)/float64(N)*sum)
+	return pmSchemeFactor * dF / referenceForce(N, L)
+}
+
+// p3mCost는 스텝당 PM, PP 비용을 추정합니다.
+//
+//	PM: FFT 2회 (5·M·log₂M, M = Ng³) + 기울기 3회 + CIC 할당·보간 (8노드 × 4회)
+//	PP: 27개 셀(셀 크기 rc) 안의 후보 쌍 N · 27·rc³·N/L³
+func p3mCost(N int, L float64, ng int, rCut float64) (float64, float64) {
+	M := float64(ng * ng * ng)
+	pm := 2*5*M*math.Log2(M) + 3*2*M + 4*8*tuneAssignCost*float64(N)
+	density := float64(N) / (L * L * L)
+	pp := float64(N) * 27 * rCut * rCut * rCut * density * tunePairCost
+	return pm, pp
+}
+
+// bisect는 [lo, hi]에서 단조 조건 ok가 바뀌는 경계를 이분법으로 찾아
+// ok를 만족하는 쪽의 끝을 반환합니다. 구간 전체에서 ok가 같으면 ok(hi)가 참일 때 hi,
+// 아니면 lo를 반환합니다.
+func bisect(ok func(float64) bool, lo, hi float64) float64 {
+	okLo, okHi := ok(lo), ok(hi)
+	if okLo == okHi {
+		if okHi {
+			return hi
+		}
+		return lo
+	}
+	for i := 0; i < 100; i++ {
+		mid := 0.5 * (lo + hi)
+		if ok(mid) == okLo {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	if okLo {
+		return lo
+	}
+	return hi
+}
@@ -0,0 +1,41 @@
+package atom3D
+
+import (
+	"math"
+	"testing"
+)
+
+// 튜닝 결과는 요청 정확도를 만족해야 하고, 실제 힘 오차도 같은 크기여야 합니다.
+func TestTuneP3M(t *testing.T) {
+	L := 10.
+	N := 216
+	for _, accuracy := range []float64{1e-1, 1e-2} {
+		tuning := TuneP3M(N, L, accuracy)
+		t.Logf("accuracy %.0e: Ng=%d alpha=%.3f rc=%.3f err=%.2e (R %.2e, K %.2e) PM=%.2e PP=%.2e",
+			accuracy, tuning.Ng, tuning.Alpha, tuning.RCut, tuning.Error,
+			tuning.RealSpaceError, tuning.KSpaceError, tuning.PMCost, tuning.PPCost)
+		if tuning.Error > accuracy*(1+1e-9) {
+			t.Errorf("estimated error %.3e exceeds requested %.0e", tuning.Error, accuracy)
+		}
+
+		sim := ewaldSimulator(randomPositions(N, L, 11), L)
+		tuning.Apply(sim)
+		exact := NewEwaldSum(L, 1.).Accelerations(sim)
+		got := tuning.P3M(1.).Accelerations(sim)
+
+		// CompareForces는 F_rms로 정규화하므로 F_ref 기준으로 다시 맞춥니다.
+		sumDiff := 0.
+		for i := range exact {
+			d := got[i].Sub(exact[i])
+			sumDiff += d.Dot(d)
+		}
+		measured := math.Sqrt(sumDiff/float64(N)) / referenceForce(N, L)
+		t.Logf("  measured rms / F_ref = %.2e", measured)
+		if measured > 1.5*accuracy {
+			t.Errorf("accuracy %.0e: measured error %.3e", accuracy, measured)
+		}
+		if tuning.RCut > L/3 || tuning.GridSize != sim.GridSize {
+			t.Errorf("accuracy %.0e: rc = %v, grid size = %v", accuracy, tuning.RCut, sim.GridSize)
+		}
+	}
+}
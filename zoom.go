@@ -0,0 +1,360 @@
+package atom3D
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/dsp/fourier"
+)
+
+// ── NestedPM ─────────────────────────────────────────────────────────────────
+
+// NestedPM은 주기 박스 전체의 PM 격자 위에, 밀도가 높은 영역마다 더 촘촘한 PM 패치(zoom)를
+// 얹어 헤일로 안의 힘 해상도를 높이는 중력 ForceField입니다. PP 보정 없는 순수 PM입니다.
+//
+//  1. 부모 격자 (Ng³, 주기): FFT로 ∇²Φ = 4πG(ρ - ρ̄) 를 한 번 풀고, 그 Φ의 기울기로 모든 파티클의 힘을 구합니다.
+//  2. 패치 배치: 부모 AssignDensity에서 ρ/ρ̄ > Threshold 인 셀을 밀도 순으로 골라
+//     PatchCells³ 부모 셀 크기의 패치를 놓습니다 (이미 덮인 셀은 건너뜀, 최대 MaxPatches개).
+//     박스 경계에 걸친 패치는 주기 경계를 따라 반대편으로 이어집니다.
+//  3. 패치 격자 (간격 dx/Refine): 패치 경계의 퍼텐셜을 부모 퍼텐셜에서 보간한 Dirichlet 문제를
+//     3D 이산 사인 변환(DST-I)으로 풀고, 유한차분 기울기로 패치 안쪽 파티클의 힘을 바꿉니다.
+//
+// 패치 밖 질량은 경계값을 통해서만 들어오므로 (패치 안에서 조화 함수), 부모 퍼텐셜이
+// 정확할수록 패치 해도 정확합니다. 패치 경계에서 한 부모 셀 이내의 파티클은 부모 힘을 씁니다.
+type NestedPM struct {
+	Ng int     // 부모 PM 격자 크기 (차원당)
+	L  float64 // 주기 박스 크기
+	G  float64 // 중력 상수
+
+	Refine     int     // 패치 격자 간격 = 부모 간격 / Refine
+	PatchCells int     // 패치 한 변의 부모 셀 수
+	Threshold  float64 // 패치를 놓을 부모 셀의 과밀도 ρ/ρ̄
+	MaxPatches int     // 최대 패치 수
+
+	Patches []ZoomPatch // 마지막 Accelerations에서 배치한 패치
+
+	parent *P3M
+}
+
+// ZoomPatch는 부모 격자 노드에 맞춰 놓인 정육면체 세분 영역입니다.
+// 패치 격자 노드는 Origin + (a, b, c)·H, a, b, c = 0..N 이며 경계 노드(0 또는 N)는 Dirichlet 경계입니다.
+// 주기 박스이므로 패치는 박스 밖으로 나갈 수 있고, 그 부분은 반대편 파티클의 이미지를 담습니다 (local).
+type ZoomPatch struct {
+	Origin Vector  // 최소 모서리 (부모 격자 노드, 박스 밖일 수 있음)
+	H      float64 // 패치 격자 간격
+	N      int     // 한 변의 셀 수 (노드 N+1개)
+}
+
+// NewNestedPM은 Refine = 4, PatchCells = 8, Threshold = 8, MaxPatches = 8 인 NestedPM을 생성합니다.
+func NewNestedPM(ng int, L, G float64) *NestedPM {
+	return &NestedPM{
+		Ng:         ng,
+		L:          L,
+		G:          G,
+		Refine:     4,
+		PatchCells: 8,
+		Threshold:  8,
+		MaxPatches: 8,
+	}
+}
+
+// parentPM은 부모 격자의 순수 PM 솔버입니다. Alpha = +Inf 이면 Ewald Green 함수의
+// Gaussian 필터 exp(-k²/4α²)가 1이 되어 전체 1/r 퍼텐셜을 풉니다.
+func (z *NestedPM) parentPM() *P3M {
+	if z.parent == nil || z.parent.Ng != z.Ng || z.parent.L != z.L || z.parent.G != z.G {
+		z.parent = &P3M{Ng: z.Ng, L: z.L, G: z.G, Alpha: math.Inf(1)}
+	}
+	return z.parent
+}
+
+// local은 위치 r의 패치 격자 좌표 (r - Origin)/H 를 반환합니다. 변위를 축마다 [0, L) 로 감아
+// 박스 경계에 걸친 패치도 반대편 파티클을 담게 합니다 (패치 한 변 < L 이므로 이미지는 하나).
+func (patch ZoomPatch) local(r Vector, L float64) Vector {
+	d := r.Sub(patch.Origin)
+	wrap := func(x float64) float64 { return x - L*math.Floor(x/L) }
+	return Vector{wrap(d.X), wrap(d.Y), wrap(d.Z)}.Div(patch.H)
+}
+
+// Accelerations는 ForceField 인터페이스 구현입니다. Patches도 갱신합니다.
+func (z *NestedPM) Accelerations(sim *Simulator) []Vector {
+	p := z.parentPM()
+	p.parallel = sim.Parallel
+	rho := p.AssignDensity(sim.Pos, sim.Mass)
+	phi := p.SolvePotential(rho)
+	acc := z.parentForces(sim.Pos, phi)
+
+	z.Patches = z.placePatches(rho)
+	background := 0.0
+	for _, m := range sim.Mass {
+		background += m
+	}
+	background /= z.L * z.L * z.L
+
+	// 경계값 보간은 부모 P3M의 작업 버퍼를 쓰므로 먼저 패치마다 차례로 채웁니다.
+	patchPhi := make([][]float64, len(z.Patches))
+	for k, patch := range z.Patches {
+		patchPhi[k] = z.patchBoundary(patch, phi)
+	}
+
+	// 패치마다 독립이므로 병렬로 풀고, 겹치는 영역은 앞선(더 밀도가 높은) 패치를 씁니다.
+	fine := make([]map[int]Vector, len(z.Patches))
+	sim.Parallel.tasks().For(len(z.Patches), func(_, lo, hi int) {
+		for k := lo; k < hi; k++ {
+			fine[k] = z.patchForces(z.Patches[k], sim, patchPhi[k], background)
+		}
+	})
+
+	done := make([]bool, sim.N)
+	for _, forces := range fine {
+		for i, a := range forces {
+			if !done[i] {
+				acc[i] = a
+				done[i] = true
+			}
+		}
+	}
+	return acc
+}
+
+// parentForces는 부모 격자 퍼텐셜 phi의 FD2 기울기를 파티클 위치로 보간한 가속도입니다.
+// PMForces와 같은 값이지만 이미 푼 phi를 쓰므로 밀도 할당과 FFT를 되풀이하지 않습니다.
+func (z *NestedPM) parentForces(pos []Vector, phi []float64) []Vector {
+	p := z.parentPM()
+	p.finiteDifferenceForces(phi)
+	f := p.interpolate(pos, p.scratch.grid[:3], nil)
+	acc := make([]Vector, len(pos))
+	for i := range acc {
+		acc[i] = Vector{f[0][i], f[1][i], f[2][i]}
+	}
+	return acc
+}
+
+// placePatches는 과밀도가 Threshold를 넘는 부모 셀을 밀도 내림차순으로 덮는 패치를 고릅니다.
+// 패치는 고른 셀을 중심에 두며, 박스 경계에 걸치면 주기 경계를 따라 반대편으로 이어집니다.
+func (z *NestedPM) placePatches(rho []float64) []ZoomPatch {
+	ng := z.Ng
+	cells := z.PatchCells
+	if cells >= ng {
+		cells = ng - 1
+	}
+	mean := 0.0
+	for _, v := range rho {
+		mean += v
+	}
+	mean /= float64(len(rho))
+
+	dense := []int{}
+	for i, v := range rho {
+		if mean > 0 && v > z.Threshold*mean {
+			dense = append(dense, i)
+		}
+	}
+	sort.Slice(dense, func(a, b int) bool { return rho[dense[a]] > rho[dense[b]] })
+
+	dx := z.L / float64(ng)
+	refine := max(z.Refine, 1)
+	var patches []ZoomPatch
+	var corners [][3]int
+	for _, cell := range dense {
+		if len(patches) >= z.MaxPatches {
+			break
+		}
+		c := [3]int{cell % ng, (cell / ng) % ng, cell / (ng * ng)}
+		covered := false
+		for _, lo := range corners {
+			if Mod(c[0]-lo[0], ng) <= cells && Mod(c[1]-lo[1], ng) <= cells && Mod(c[2]-lo[2], ng) <= cells {
+				covered = true
+				break
+			}
+		}
+		if covered {
+			continue
+		}
+
+		var lo [3]int
+		for a := range lo {
+			lo[a] = c[a] - cells/2 // 음수나 ng-1-cells 초과면 박스 경계에 걸침
+		}
+		corners = append(corners, lo)
+		node := func(i int) float64 { return (float64(i)+0.5)*dx - z.L/2 } // 부모 노드 위치 (stencil 규약)
+		patches = append(patches, ZoomPatch{
+			Origin: Vector{node(lo[0]), node(lo[1]), node(lo[2])},
+			H:      dx / float64(refine),
+			N:      cells * refine,
+		})
+	}
+	return patches
+}
+
+// patchBoundary는 패치 격자 퍼텐셜 배열 (n+1)³ 을 만들고 경계 노드를 부모 퍼텐셜의
+// CIC(삼선형) 보간으로 채웁니다 (내부 노드는 0).
+func (z *NestedPM) patchBoundary(patch ZoomPatch, parentPhi []float64) []float64 {
+	n := patch.N
+	nn := n + 1
+	h := patch.H
+	index := func(a, b, c int) int { return a + nn*(b+nn*c) }
+
+	phi := make([]float64, nn*nn*nn)
+	var boundary []Vector
+	var boundaryIdx []int
+	for c := 0; c <= n; c++ {
+		for b := 0; b <= n; b++ {
+			for a := 0; a <= n; a++ {
+				if a == 0 || b == 0 || c == 0 || a == n || b == n || c == n {
+					boundary = append(boundary, patch.Origin.Add(Vector{float64(a), float64(b), float64(c)}.Mul(h)))
+					boundaryIdx = append(boundaryIdx, index(a, b, c))
+				}
+			}
+		}
+	}
+	for k, v := range z.parentPM().interpolate(boundary, [][]float64{parentPhi}, nil)[0] {
+		phi[boundaryIdx[k]] = v
+	}
+	return phi
+}
+
+// patchForces는 경계값이 채워진 패치 퍼텐셜 phi (patchBoundary)로 Dirichlet 문제를 풀고,
+// 경계에서 한 부모 셀 이상 안쪽에 있는 파티클의 가속도를 반환합니다 (파티클 번호 → 가속도).
+func (z *NestedPM) patchForces(patch ZoomPatch, sim *Simulator, phi []float64, background float64) map[int]Vector {
+	n := patch.N
+	nn := n + 1
+	h := patch.H
+	index := func(a, b, c int) int { return a + nn*(b+nn*c) }
+
+	// 원천항 4πG(ρ - ρ̄), 패치 안 파티클을 패치 격자에 CIC 할당
+	rhs := make([]float64, nn*nn*nn)
+	cellMass := 4 * math.Pi * z.G / (h * h * h)
+	inside := []int{}
+	for i, r := range sim.Pos {
+		g := patch.local(r, z.L)
+		if g.X < 0 || g.Y < 0 || g.Z < 0 || g.X > float64(n) || g.Y > float64(n) || g.Z > float64(n) {
+			continue
+		}
+		inside = append(inside, i)
+		ix, iy, iz := int(g.X), int(g.Y), int(g.Z)
+		fx, fy, fz := g.X-float64(ix), g.Y-float64(iy), g.Z-float64(iz)
+		for dc := 0; dc < 2; dc++ {
+			for db := 0; db < 2; db++ {
+				for da := 0; da < 2; da++ {
+					a, b, c := ix+da, iy+db, iz+dc
+					if a > n || b > n || c > n {
+						continue
+					}
+					w := (1 - fx + float64(da)*(2*fx-1)) * (1 - fy + float64(db)*(2*fy-1)) * (1 - fz + float64(dc)*(2*fz-1))
+					rhs[index(a, b, c)] += cellMass * sim.Mass[i] * w
+				}
+			}
+		}
+	}
+	for i := range rhs {
+		rhs[i] -= 4 * math.Pi * z.G * background
+	}
+
+	dirichletPoisson(phi, rhs, n, h)
+
+	// 안쪽 파티클: 중앙 차분 기울기를 CIC 보간
+	margin := math.Round(z.L / float64(z.Ng) / h) // 부모 셀 하나 (실제 세분 배율)
+	forces := map[int]Vector{}
+	grad := func(a, b, c int) Vector {
+		return Vector{
+			phi[index(a+1, b, c)] - phi[index(a-1, b, c)],
+			phi[index(a, b+1, c)] - phi[index(a, b-1, c)],
+			phi[index(a, b, c+1)] - phi[index(a, b, c-1)],
+		}.Mul(-1 / (2 * h))
+	}
+	for _, i := range inside {
+		g := patch.local(sim.Pos[i], z.L)
+		lo, hi := margin, float64(n)-margin
+		if g.X < lo || g.Y < lo || g.Z < lo || g.X > hi || g.Y > hi || g.Z > hi {
+			continue
+		}
+		ix, iy, iz := int(g.X), int(g.Y), int(g.Z)
+		fx, fy, fz := g.X-float64(ix), g.Y-float64(iy), g.Z-float64(iz)
+		var f Vector
+		for dc := 0; dc < 2; dc++ {
+			for db := 0; db < 2; db++ {
+				for da := 0; da < 2; da++ {
+					w := (1 - fx + float64(da)*(2*fx-1)) * (1 - fy + float64(db)*(2*fy-1)) * (1 - fz + float64(dc)*(2*fz-1))
+					f = f.Add(grad(ix+da, iy+db, iz+dc).Mul(w))
+				}
+			}
+		}
+		forces[i] = f
+	}
+	return forces
+}
+
+// dirichletPoisson은 (n+1)³ 격자에서 7점 이산 라플라시안 ∇²φ = rhs 를 풉니다.
+// phi의 경계 노드(어느 축이든 0 또는 n)는 주어진 값으로 고정하고 내부 노드를 채웁니다.
+//
+// 경계값을 우변으로 옮긴 뒤 (b = rhs - Σ 경계 이웃/h²), 내부 (n-1)³ 미지수에 3D DST-I을 적용하면
+// 라플라시안이 대각화됩니다: λ_k = Σ_axis (2cos(πk/n) - 2)/h²,  k = 1..n-1.
+func dirichletPoisson(phi, rhs []float64, n int, h float64) {
+	m := n - 1
+	if m < 1 {
+		return
+	}
+	nn := n + 1
+	index := func(a, b, c int) int { return a + nn*(b+nn*c) }
+	boundary := func(a, b, c int) bool {
+		return a == 0 || b == 0 || c == 0 || a == n || b == n || c == n
+	}
+
+	h2 := h * h
+	work := make([]float64, m*m*m)
+	for c := 1; c < n; c++ {
+		for b := 1; b < n; b++ {
+			for a := 1; a < n; a++ {
+				v := rhs[index(a, b, c)]
+				for _, nb := range [6][3]int{{a - 1, b, c}, {a + 1, b, c}, {a, b - 1, c}, {a, b + 1, c}, {a, b, c - 1}, {a, b, c + 1}} {
+					if boundary(nb[0], nb[1], nb[2]) {
+						v -= phi[index(nb[0], nb[1], nb[2])] / h2
+					}
+				}
+				work[(a-1)+m*((b-1)+m*(c-1))] = v
+			}
+		}
+	}
+
+	dst := fourier.NewDST(m)
+	line := make([]float64, m)
+	transform := func() {
+		for _, stride := range [3]int{1, m, m * m} {
+			for base := 0; base < m*m*m; base++ {
+				if (base/stride)%m != 0 {
+					continue // 각 행의 시작점만
+				}
+				for i := range line {
+					line[i] = work[base+i*stride]
+				}
+				dst.Transform(line, line)
+				for i := range line {
+					work[base+i*stride] = line[i]
+				}
+			}
+		}
+	}
+
+	transform()
+	eig := make([]float64, m)
+	for k := range eig {
+		eig[k] = (2*math.Cos(math.Pi*float64(k+1)/float64(n)) - 2) / h2
+	}
+	norm := 1 / math.Pow(2*float64(n), 3) // DST-I 두 번 = 2(m+1) = 2n 배 (축마다)
+	for c := 0; c < m; c++ {
+		for b := 0; b < m; b++ {
+			for a := 0; a < m; a++ {
+				work[a+m*(b+m*c)] *= norm / (eig[a] + eig[b] + eig[c])
+			}
+		}
+	}
+	transform()
+
+	for c := 1; c < n; c++ {
+		for b := 1; b < n; b++ {
+			for a := 1; a < n; a++ {
+				phi[index(a, b, c)] = work[(a-1)+m*((b-1)+m*(c-1))]
+			}
+		}
+	}
+}
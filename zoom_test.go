@@ -0,0 +1,139 @@
+package atom3D
+
+import (
+	"math"
+	"testing"
+)
+
+// 이산 조화 함수와 2차 다항식은 7점 라플라시안에서 정확하므로 Dirichlet 해가 그대로 복원되어야 합니다.
+func TestDirichletPoisson(t *testing.T) {
+	n, h := 12, 0.3
+	nn := n + 1
+	cases := []struct {
+		name string
+		f    func(x, y, z float64) float64
+		lap  float64
+	}{
+		{"harmonic", func(x, y, z float64) float64 { return x*x - y*y + 3*z - x*y }, 0},
+		{"quadratic", func(x, y, z float64) float64 { return x*x + 2*y*y + 0.5*z*z + y*z }, 7},
+	}
+	for _, c := range cases {
+		phi := make([]float64, nn*nn*nn)
+		rhs := make([]float64, nn*nn*nn)
+		for k := 0; k <= n; k++ {
+			for j := 0; j <= n; j++ {
+				for i := 0; i <= n; i++ {
+					idx := i + nn*(j+nn*k)
+					rhs[idx] = c.lap
+					if i == 0 || j == 0 || k == 0 || i == n || j == n || k == n {
+						phi[idx] = c.f(float64(i)*h, float64(j)*h, float64(k)*h)
+					}
+				}
+			}
+		}
+		dirichletPoisson(phi, rhs, n, h)
+		worst := 0.
+		for k := 0; k <= n; k++ {
+			for j := 0; j <= n; j++ {
+				for i := 0; i <= n; i++ {
+					want := c.f(float64(i)*h, float64(j)*h, float64(k)*h)
+					worst = math.Max(worst, math.Abs(phi[i+nn*(j+nn*k)]-want))
+				}
+			}
+		}
+		if worst > 1e-10 {
+			t.Errorf("%s: max error %.3e", c.name, worst)
+		}
+	}
+}
+
+// 균일 배경 속의 무거운 점질량 주위에서, 패치가 부모 격자 간격 수준 거리의 힘을 바로잡아야 합니다.
+// 질량 0인 탐침 파티클을 0.25–1.2 (부모 간격 0.31) 거리에 두고 Ewald 합과 비교합니다.
+// 박스 모서리 근처의 점질량은 주기 경계를 따라 이어진 패치가 덮어야 합니다.
+func TestNestedPM(t *testing.T) {
+	L := 10.
+	for _, center := range []Vector{{1.3, -0.7, 0.4}, {L/2 - 0.1, -L/2 + 0.05, 0.4}} {
+		pos := append(randomPositions(500, L, 5), center)
+		probes := len(pos)
+		for _, d := range plummerPositions(200, 0.5, 7) {
+			if r := d.Abs(); r > 0.25 && r < 1.2 {
+				pos = append(pos, center.Add(d))
+			}
+		}
+		sim := ewaldSimulator(pos, L)
+		sim.PeriodicBoundary(L)
+		for i := range sim.Mass {
+			sim.Mass[i] = 0
+			if i < probes-1 {
+				sim.Mass[i] = 0.1
+			}
+		}
+		sim.Mass[probes-1] = 100
+		exact := NewEwaldSum(L, 1.).Accelerations(sim)
+
+		zoom := NewNestedPM(32, L, 1.)
+		acc := zoom.Accelerations(sim)
+		found := false
+		for _, patch := range zoom.Patches {
+			d := patch.local(center, L)
+			if d.X > 0 && d.Y > 0 && d.Z > 0 && d.X < float64(patch.N) && d.Y < float64(patch.N) && d.Z < float64(patch.N) {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("center %v: patches %v do not cover the point mass", center, zoom.Patches)
+		}
+
+		parent := zoom.parentPM().PMForces(sim.Pos, sim.Mass)
+		fine := CompareForces(acc[probes:], exact[probes:]).RMS
+		coarse := CompareForces(parent[probes:], exact[probes:]).RMS
+		t.Logf("center %v: %d patches; probe rms = %.3e (zoom), %.3e (parent PM)", center, len(zoom.Patches), fine, coarse)
+		if fine > 8e-2 {
+			t.Errorf("center %v: zoom probe rms force error %.3e > 8e-2", center, fine)
+		}
+		if fine > 0.2*coarse {
+			t.Errorf("center %v: zoom patches did not refine forces near the point mass: %.3e vs %.3e", center, fine, coarse)
+		}
+
+		// 세분을 높이면 오차가 계속 줄어야 합니다.
+		zoom.Refine = 8
+		if finer := CompareForces(zoom.Accelerations(sim)[probes:], exact[probes:]).RMS; finer > 0.5*fine {
+			t.Errorf("center %v: Refine = 8 rms %.3e, not below half of Refine = 4 (%.3e)", center, finer, fine)
+		}
+	}
+}
+
+// 패치가 없으면 NestedPM은 한 번 푼 부모 퍼텐셜에서 PMForces와 같은 힘을 내야 합니다.
+// 패치 경계에서 부모 셀 하나 안의 파티클은 부모 힘을 씁니다. PatchCells >= Ng 라서 패치 셀 수가
+// 줄어도 그 여유는 실제 세분 배율(Refine) 만큼의 패치 셀입니다.
+func TestNestedPMParent(t *testing.T) {
+	L := 10.
+	sim := ewaldSimulator(randomPositions(300, L, 9), L)
+	zoom := NewNestedPM(16, L, 1.)
+	zoom.Threshold = math.Inf(1)
+	acc := zoom.Accelerations(sim)
+	if len(zoom.Patches) != 0 {
+		t.Fatalf("%d patches with infinite threshold", len(zoom.Patches))
+	}
+	want := zoom.parentPM().PMForces(sim.Pos, sim.Mass)
+	if err := CompareForces(acc, want); err.Max > 1e-12 {
+		t.Errorf("parent forces differ from PMForces: max error %.3e", err.Max)
+	}
+
+	zoom.Threshold = 8
+	zoom.PatchCells = 20 // > Ng: 패치는 Ng-1 = 15 부모 셀, N = 60
+	sim.Mass[0] = 100
+	zoom.Accelerations(sim)
+	if len(zoom.Patches) == 0 || zoom.Patches[0].N != 15*zoom.Refine {
+		t.Fatalf("patches %v, want a first patch of %d cells", zoom.Patches, 15*zoom.Refine)
+	}
+	patch := zoom.Patches[0]
+	mid := float64(patch.N) / 2
+	edge := ewaldSimulator(append(append([]Vector{}, sim.Pos...), patch.Origin.Add(Vector{3.5, mid, mid}.Mul(patch.H))), L)
+	copy(edge.Mass, sim.Mass)
+	edge.Mass[sim.N] = 0
+	acc = zoom.Accelerations(edge)
+	if want := zoom.parentPM().PMForces(edge.Pos, edge.Mass)[sim.N]; acc[sim.N] != want {
+		t.Errorf("particle 3.5 patch cells from the edge: acc %v, want parent %v", acc[sim.N], want)
+	}
+}